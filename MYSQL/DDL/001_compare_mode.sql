@@ -0,0 +1,7 @@
+-- Output comparison strategy, see service.NewComparator for the accepted specs.
+-- A testcase value overrides the question value; both default to the legacy comparator.
+ALTER TABLE senior_project.questions
+    ADD COLUMN compare_mode VARCHAR(255) NOT NULL DEFAULT '';
+
+ALTER TABLE senior_project.testcases
+    ADD COLUMN compare_mode VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+-- Verdict codes from model.Verdict (AC, WA, PA, RE, CE, TLE, MLE, OLE, IE), stored next to status.
+ALTER TABLE senior_project.student_testcases_v2
+    ADD COLUMN verdict VARCHAR(8) NOT NULL DEFAULT '';
+
+-- Aggregated over the testcases of the submission, see model.AggregateVerdict.
+ALTER TABLE senior_project.student_question_files_v2
+    ADD COLUMN verdict VARCHAR(8) NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- Executor used to run submissions, see executer.ExecutorNames (python, c, node).
+ALTER TABLE senior_project.questions
+    ADD COLUMN language VARCHAR(32) NOT NULL DEFAULT 'python';
@@ -0,0 +1,3 @@
+-- Python interpreter name from PYTHON_INTERPRETERS; empty uses PYTHON_DEFAULT_INTERPRETER.
+ALTER TABLE senior_project.questions
+    ADD COLUMN interpreter VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,19 @@
+-- Further files of multi-file submissions; the main file stays in student_question_files_v2.sourcecode.
+CREATE TABLE IF NOT EXISTS senior_project.student_question_file_v2_sources (
+    source_file_id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    student_question_file_v2_id INT NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    content MEDIUMTEXT NOT NULL,
+    UNIQUE KEY uq_source_file (student_question_file_v2_id, file_name)
+);
+
+-- Files attached to testcases, see model.TestcaseFile. Input files are written to the working
+-- directory before the run; expected files are compared with what the submission wrote.
+CREATE TABLE IF NOT EXISTS senior_project.testcase_files (
+    testcase_file_id INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    testcase_id INT NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    kind VARCHAR(16) NOT NULL DEFAULT 'input',
+    content MEDIUMBLOB NOT NULL,
+    UNIQUE KEY uq_testcase_file (testcase_id, kind, file_name)
+);
@@ -0,0 +1,5 @@
+-- Function testcases call this function of the submission instead of running it as a program:
+-- testcase_input holds the arguments as JSON, a positional array or a keyword object, and
+-- testcase_output the JSON of the expected return value. Empty keeps the stdin/stdout testcase.
+ALTER TABLE senior_project.testcases
+    ADD COLUMN function_name VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,10 @@
+-- Instructor test module run against Python submissions, which it imports as "submission".
+-- test_framework is 'unittest' or 'pytest'; an empty test_suite means the question has none.
+ALTER TABLE senior_project.questions
+    ADD COLUMN test_suite MEDIUMTEXT NULL,
+    ADD COLUMN test_framework VARCHAR(16) NOT NULL DEFAULT 'unittest';
+
+-- Test of the suite a testcase is graded by, as TestClass.test_method or test_function.
+-- Empty keeps the stdin/stdout testcase.
+ALTER TABLE senior_project.testcases
+    ADD COLUMN test_name VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+-- Special judge of a question, see service.Checker. When set, it decides every stdin/stdout
+-- testcase in place of the comparator. checker_language selects its executor like language.
+ALTER TABLE senior_project.questions
+    ADD COLUMN checker MEDIUMTEXT NULL,
+    ADD COLUMN checker_language VARCHAR(32) NOT NULL DEFAULT '';
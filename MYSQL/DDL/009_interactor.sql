@@ -0,0 +1,6 @@
+-- Interactor of a question, see service.Interactor. When set, every stdin/stdout testcase
+-- runs the submission against it and it decides the verdict. interactor_language selects
+-- its executor like language.
+ALTER TABLE senior_project.questions
+    ADD COLUMN interactor MEDIUMTEXT NULL,
+    ADD COLUMN interactor_language VARCHAR(32) NOT NULL DEFAULT '';
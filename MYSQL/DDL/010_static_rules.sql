@@ -0,0 +1,4 @@
+-- Static rules of a question as JSON, see service.StaticRules, checked before any testcase
+-- runs. Empty means no rules.
+ALTER TABLE senior_project.questions
+    ADD COLUMN static_rules TEXT NULL;
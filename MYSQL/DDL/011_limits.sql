@@ -0,0 +1,15 @@
+-- Time and memory limits of a run of the submission, see service.testLimits. 0 on a
+-- question falls back to the configured defaults, 0 on a testcase to its question's limits.
+ALTER TABLE senior_project.questions
+    ADD COLUMN time_limit_ms INT NOT NULL DEFAULT 0,
+    ADD COLUMN memory_limit_mb INT NOT NULL DEFAULT 0;
+
+ALTER TABLE senior_project.testcases
+    ADD COLUMN time_limit_ms INT NOT NULL DEFAULT 0,
+    ADD COLUMN memory_limit_mb INT NOT NULL DEFAULT 0;
+
+-- The limits a result was graded with, after the defaults and any override for the run.
+-- memory_limit_mb is 0 when memory was not limited.
+ALTER TABLE senior_project.student_testcases_v2
+    ADD COLUMN time_limit_ms INT NOT NULL DEFAULT 0,
+    ADD COLUMN memory_limit_mb INT NOT NULL DEFAULT 0;
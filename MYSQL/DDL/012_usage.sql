@@ -0,0 +1,7 @@
+-- Time and memory the run of the submission took for a testcase: wall time, user + system
+-- CPU time and peak resident set size. 0 when the testcase was not graded by a run of its
+-- own, such as a compile error, a broken static rule or a test of the question's test suite.
+ALTER TABLE senior_project.student_testcases_v2
+    ADD COLUMN wall_time_ms INT NOT NULL DEFAULT 0,
+    ADD COLUMN cpu_time_ms INT NOT NULL DEFAULT 0,
+    ADD COLUMN peak_memory_kb INT NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+-- Efficiency scoring, see service.Efficiency. reference_solution is a model answer in the
+-- question's language, run on each testcase the submission passes; an accepted run keeps
+-- full credit within efficiency_multiple times its CPU time. 0 does not score efficiency.
+ALTER TABLE senior_project.questions
+    ADD COLUMN reference_solution MEDIUMTEXT NULL,
+    ADD COLUMN efficiency_multiple DOUBLE NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+INSERT INTO student_question_file_v2_sources (
+    student_question_file_v2_id,
+    file_name,
+    content
+)
+VALUES (?, ?, ?)
+ON DUPLICATE KEY UPDATE content = VALUES(content);
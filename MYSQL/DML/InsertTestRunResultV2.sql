@@ -1,3 +1,3 @@
 INSERT INTO senior_project.student_testcases_v2
-(student_question_file_v2_id, testcase_id, score, status, test_output_text, created_at, updated_at)
-VALUES(?, ?, ?, ?, ?, NOW(), NOW());
\ No newline at end of file
+(student_question_file_v2_id, testcase_id, score, status, verdict, test_output_text, time_limit_ms, memory_limit_mb, wall_time_ms, cpu_time_ms, peak_memory_kb, created_at, updated_at)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW());
\ No newline at end of file
@@ -0,0 +1,19 @@
+SELECT 
+		q.question_id 
+		, q.total_score 
+		, q.compare_mode 
+		, q.language 
+		, q.interpreter 
+		, COALESCE(q.test_suite, '') AS test_suite 
+		, q.test_framework 
+		, COALESCE(q.checker, '') AS checker 
+		, q.checker_language 
+		, COALESCE(q.interactor, '') AS interactor 
+		, q.interactor_language 
+		, COALESCE(q.static_rules, '') AS static_rules 
+		, q.time_limit_ms 
+		, q.memory_limit_mb 
+		, COALESCE(q.reference_solution, '') AS reference_solution 
+		, q.efficiency_multiple 
+	FROM questions q
+	WHERE q.question_id = ?
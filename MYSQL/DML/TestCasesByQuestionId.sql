@@ -6,6 +6,11 @@ SELECT
 		, tc.testcase_output 
 		, tc.score
 		, tc.regex_match 
+		, tc.compare_mode 
+		, tc.function_name 
+		, tc.test_name 
+		, tc.time_limit_ms 
+		, tc.memory_limit_mb 
 		, tc.created_at 
 		, tc.updated_at 
 	FROM testcases tc
@@ -0,0 +1,9 @@
+SELECT 
+		tf.testcase_file_id 
+		, tf.testcase_id 
+		, tf.file_name 
+		, tf.kind 
+		, tf.content 
+	FROM testcase_files tf
+	JOIN testcases tc ON tc.testcase_id = tf.testcase_id
+	WHERE tc.question_id = ?
\ No newline at end of file
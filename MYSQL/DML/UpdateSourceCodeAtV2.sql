@@ -6,6 +6,7 @@ SET student_question_file_id = ?,
     sourcecode = ?,
     version = ?,
     score = ?,
+    verdict = ?,
     updated_at = NOW()
 WHERE student_question_file_v2_id = ?
     
\ No newline at end of file
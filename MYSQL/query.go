@@ -24,4 +24,13 @@ var InsertTestRunResultV2 string
 var GetSourceCodeInfoV2FromOldIdAndVersion string
 
 //go:embed DML/CalculateSourceCodeScore.sql
-var CalculateSourceCodeScoreV2 string
\ No newline at end of file
+var CalculateSourceCodeScoreV2 string
+
+//go:embed DML/QuestionById.sql
+var QuestionById string
+
+//go:embed DML/TestcaseFilesByQuestionId.sql
+var TestcaseFilesByQuestionId string
+
+//go:embed DML/InsertSourceFileV2.sql
+var InsertSourceFileV2 string
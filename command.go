@@ -5,6 +5,7 @@ package main
 import (
 	"context"
 	"python-runner/configuration"
+	"python-runner/executer"
 	"python-runner/service"
 
 	"github.com/urfave/cli/v3"
@@ -25,10 +26,17 @@ func createCommand() *cli.Command {
 						Aliases: []string{"f"},
 						Usage:   "python file to run",
 					},
+					interpreterFlag(),
+					warmWorkersFlag(),
+					parallelTestcasesFlag(),
+					timeLimitFlag(),
+					memoryLimitFlag(),
+					efficiencyMultipleFlag(),
 				},
+				Before: checkExecutors,
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					file := cmd.String("file")
-					return service.GradeFileByOldId(ctx, file)
+					return service.GradeFileByOldIdWithOptions(ctx, file, gradeOptions(cmd))
 				},
 			},
 			{
@@ -53,18 +61,97 @@ func createCommand() *cli.Command {
 					&cli.IntFlag{
 						Name:    "workers",
 						Aliases: []string{"w"},
-						Usage:   "maximum number of concurrent workers (default: 4)",
+						Usage:   "maximum number of concurrent workers, and of programs running at once (default: 4)",
 						Value:   4,
 					},
+					interpreterFlag(),
+					warmWorkersFlag(),
+					parallelTestcasesFlag(),
+					timeLimitFlag(),
+					memoryLimitFlag(),
+					efficiencyMultipleFlag(),
 				},
+				Before: checkExecutors,
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					csvfile := cmd.String("csvfile")
 					latestVersionDir := cmd.String("latestVersionDir")
 					olderVersionDir := cmd.String("olderVersionDir")
 					workers := cmd.Int("workers")
-					return service.GradeFilesFromIdsCSVWithWorkers(csvfile, latestVersionDir, olderVersionDir, workers)
+					return service.GradeFilesFromIdsCSVWithOptions(csvfile, latestVersionDir, olderVersionDir, workers, gradeOptions(cmd))
 				},
 			},
 		},
 	}
 }
+
+func interpreterFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:    "interpreter",
+		Aliases: []string{"i"},
+		Usage:   "python interpreter name from PYTHON_INTERPRETERS, overriding each question's choice",
+	}
+}
+
+func warmWorkersFlag() cli.Flag {
+	return &cli.IntFlag{
+		Name:  "warm-workers",
+		Usage: "number of warm python workers to reuse across runs instead of starting the interpreter for each (default: 0, off)",
+	}
+}
+
+func parallelTestcasesFlag() cli.Flag {
+	return &cli.IntFlag{
+		Name:  "parallel-testcases",
+		Usage: "number of testcases of a submission to run at once (default: 1)",
+	}
+}
+
+func timeLimitFlag() cli.Flag {
+	return &cli.DurationFlag{
+		Name:  "time-limit",
+		Usage: "time limit of every testcase, overriding the limits of questions and testcases (e.g. 2s)",
+	}
+}
+
+func memoryLimitFlag() cli.Flag {
+	return &cli.IntFlag{
+		Name:  "memory-limit",
+		Usage: "memory limit in MB of every testcase, overriding the limits of questions and testcases",
+	}
+}
+
+func efficiencyMultipleFlag() cli.Flag {
+	return &cli.FloatFlag{
+		Name:  "efficiency-multiple",
+		Usage: "times the reference solution's CPU time an accepted run may take for full credit, overriding each question's (questions need a reference solution)",
+	}
+}
+
+// gradeOptions collects the grading options shared by the run commands
+func gradeOptions(cmd *cli.Command) service.Options {
+	return service.Options{
+		Interpreter:        cmd.String("interpreter"),
+		WarmWorkers:        cmd.Int("warm-workers"),
+		ParallelTestcases:  cmd.Int("parallel-testcases"),
+		TimeLimit:          cmd.Duration("time-limit"),
+		MemoryLimitMB:      cmd.Int("memory-limit"),
+		EfficiencyMultiple: cmd.Float("efficiency-multiple"),
+	}
+}
+
+// checkExecutors verifies the configured Python interpreters and seccomp profile before
+// any grading starts
+func checkExecutors(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+	if err := executer.CheckInterpreters(); err != nil {
+		return ctx, err
+	}
+	if err := executer.CheckSeccompProfile(); err != nil {
+		return ctx, err
+	}
+	if name := cmd.String("interpreter"); name != "" {
+		if _, err := executer.LookupInterpreter(name); err != nil {
+			return ctx, err
+		}
+	}
+	return ctx, nil
+}
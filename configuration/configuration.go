@@ -3,13 +3,16 @@ package configuration
 import (
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/viper"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	MySQL MySQLConfig `mapstructure:"mysql"`
+	MySQL    MySQLConfig    `mapstructure:"mysql"`
+	Executor ExecutorConfig `mapstructure:"executor"`
 }
 // MySQLConfig holds MySQL database configuration
 type MySQLConfig struct {
@@ -20,6 +23,33 @@ type MySQLConfig struct {
 	Database string `mapstructure:"database"`
 }
 
+// ExecutorConfig holds the default resource limits for running submissions.
+// A zero value disables the corresponding limit.
+type ExecutorConfig struct {
+	MemoryLimitMB       int `mapstructure:"memory_limit_mb"`
+	CPUTimeLimitSeconds int `mapstructure:"cpu_time_limit_seconds"`
+	ProcessLimit        int `mapstructure:"process_limit"`
+	FileSizeLimitKB     int `mapstructure:"file_size_limit_kb"`
+	OpenFilesLimit      int `mapstructure:"open_files_limit"`
+	OutputLimitKB       int `mapstructure:"output_limit_kb"`
+	// Sandbox runs submissions in a temporary directory with a scrubbed environment and,
+	// where the kernel allows it, in separate mount, network, PID and user namespaces
+	Sandbox bool `mapstructure:"sandbox"`
+	// SeccompProfile is empty for no system call filter, "default" for the built-in
+	// profile or the path of a JSON profile file
+	SeccompProfile string `mapstructure:"seccomp_profile"`
+
+	Interpreters       []InterpreterConfig `mapstructure:"interpreters"`
+	DefaultInterpreter string              `mapstructure:"default_interpreter"`
+}
+
+// InterpreterConfig names a Python interpreter and the version it must report
+type InterpreterConfig struct {
+	Name    string `mapstructure:"name"`
+	Path    string `mapstructure:"path"`
+	Version string `mapstructure:"version"`
+}
+
 var AppConfig *Config
 
 func setConfig() {
@@ -28,6 +58,51 @@ func setConfig() {
 	AppConfig.MySQL.User = GetRequiredEnv("mysql_user")
 	AppConfig.MySQL.Password = GetRequiredEnv("mysql_password")
 	AppConfig.MySQL.Database = GetRequiredEnv("mysql_database")
+
+	AppConfig.Executor.MemoryLimitMB = GetEnvInt("exec_memory_limit_mb", 256)
+	AppConfig.Executor.CPUTimeLimitSeconds = GetEnvInt("exec_cpu_time_limit_seconds", 10)
+	AppConfig.Executor.ProcessLimit = GetEnvInt("exec_process_limit", 0)
+	AppConfig.Executor.FileSizeLimitKB = GetEnvInt("exec_file_size_limit_kb", 1024)
+	AppConfig.Executor.OpenFilesLimit = GetEnvInt("exec_open_files_limit", 64)
+	AppConfig.Executor.OutputLimitKB = GetEnvInt("exec_output_limit_kb", 64)
+	AppConfig.Executor.Sandbox = GetEnvBool("exec_sandbox", true)
+	AppConfig.Executor.SeccompProfile = GetEnv("exec_seccomp_profile")
+
+	AppConfig.Executor.Interpreters = parseInterpreters(GetEnv("python_interpreters"))
+	AppConfig.Executor.DefaultInterpreter = GetEnv("python_default_interpreter")
+	if AppConfig.Executor.DefaultInterpreter == "" {
+		AppConfig.Executor.DefaultInterpreter = AppConfig.Executor.Interpreters[0].Name
+	}
+}
+
+// parseInterpreters reads PYTHON_INTERPRETERS, a comma separated list of name=path@version
+// entries where the @version part is optional. Without it, python3 from PATH is used.
+func parseInterpreters(value string) []InterpreterConfig {
+	if strings.TrimSpace(value) == "" {
+		return []InterpreterConfig{{Name: "python3", Path: "python3"}}
+	}
+
+	var interpreters []InterpreterConfig
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, target, ok := strings.Cut(entry, "=")
+		if !ok || name == "" || target == "" {
+			log.Fatalf("Environment variable python_interpreters has an invalid entry %q, expected name=path@version", entry)
+		}
+		path, version, _ := strings.Cut(target, "@")
+		interpreters = append(interpreters, InterpreterConfig{
+			Name:    strings.TrimSpace(name),
+			Path:    strings.TrimSpace(path),
+			Version: strings.TrimSpace(version),
+		})
+	}
+	if len(interpreters) == 0 {
+		log.Fatalf("Environment variable python_interpreters does not name any interpreter")
+	}
+	return interpreters
 }
 
 func init() {
@@ -50,6 +125,32 @@ func GetRequiredEnv(key string) string {
 	return value
 }
 
+// GetEnvInt returns the integer value of key, or fallback when it is unset
+func GetEnvInt(key string, fallback int) int {
+	value := viper.GetString(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Environment variable %s must be an integer, got %q", key, value)
+	}
+	return n
+}
+
+// GetEnvBool returns the boolean value of key, or fallback when it is unset
+func GetEnvBool(key string, fallback bool) bool {
+	value := viper.GetString(key)
+	if value == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("Environment variable %s must be true or false, got %q", key, value)
+	}
+	return b
+}
+
 // LoadConfig loads configuration from various sources
 func LoadConfig() error {
 	if AppConfig == nil {
@@ -91,6 +192,11 @@ func GetMySQLConfig() MySQLConfig {
 	return AppConfig.MySQL
 }
 
+// GetExecutorConfig returns the executor resource limit configuration
+func GetExecutorConfig() ExecutorConfig {
+	return AppConfig.Executor
+}
+
 // GetMySQLConnectionString returns a formatted MySQL connection string
 func GetMySQLConnectionString() string {
 	mysql := AppConfig.MySQL
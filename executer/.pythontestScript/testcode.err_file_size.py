@@ -0,0 +1,5 @@
+import tempfile
+
+with tempfile.TemporaryFile() as f:
+    for _ in range(4096):
+        f.write(b"x" * 1024)
@@ -0,0 +1,2 @@
+data = bytearray(1024 * 1024 * 1024)
+print(len(data))
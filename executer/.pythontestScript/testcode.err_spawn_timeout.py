@@ -0,0 +1,5 @@
+import subprocess
+import time
+
+subprocess.Popen(["sleep", "314"])
+time.sleep(10)
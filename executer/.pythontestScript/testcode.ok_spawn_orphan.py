@@ -0,0 +1,4 @@
+import subprocess
+
+subprocess.Popen(["sleep", "313"])
+print("parent exiting")
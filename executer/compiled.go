@@ -0,0 +1,205 @@
+package executer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// cacheMaxAge is how long a compiled binary stays in the cache after its last use
+	cacheMaxAge = 24 * time.Hour
+	// cacheMaxBytes bounds the size of the cache; the binaries used least recently go first
+	cacheMaxBytes = 256 << 20
+	// cacheMinAge keeps binaries used this recently, which may be running or about to
+	cacheMinAge = time.Minute
+)
+
+// compiledName is the file the compiler writes the binary to in its workspace
+const compiledName = "main"
+
+// compileLimits bounds the compiler, which needs more memory than the limits meant for
+// student programs allow. It is always sandboxed under the seccomp profile of the programs:
+// the sources choose the files it reads, and its diagnostics quote them.
+func compileLimits(limits Limits) Limits {
+	return Limits{
+		CPUTime:  30 * time.Second,
+		WallTime: time.Minute,
+		FileSize: 64 << 20,
+		Output:   limits.Output,
+		Sandbox:  true,
+		Seccomp:  limits.Seccomp,
+	}
+}
+
+// CExecutor compiles C submissions with gcc and runs the resulting binary. Binaries are
+// cached by a hash of the source, so the compile step runs once per submission rather
+// than once per testcase.
+type CExecutor struct {
+	// Limits bounds each run of the compiled program; the zero value runs without limits
+	Limits Limits
+	// CacheDir holds the compiled binaries; empty uses a directory under os.TempDir()
+	CacheDir string
+}
+
+func (c *CExecutor) Name() string {
+	return "c"
+}
+
+func (c *CExecutor) Version() (string, error) {
+	return commandVersion("gcc", "--version")
+}
+
+func (c *CExecutor) Execute(ctx context.Context, code string, stdin string) (ExecutionResult, error) {
+	return c.Run(ctx, Input{Code: code, Stdin: stdin})
+}
+
+// Run compiles input.Code as main.c together with the .c files among its modules, which
+// may also provide headers, and runs the binary in a working directory holding the
+// testcase files
+func (c *CExecutor) Run(ctx context.Context, input Input) (ExecutionResult, error) {
+	binary, failed, err := c.compile(ctx, input.Code, input.Modules)
+	if err != nil {
+		return ExecutionResult{}, err
+	}
+	if failed != nil {
+		return *failed, nil
+	}
+	return runCommand(ctx, c.Limits, input.commandIO(input.Files), binary)
+}
+
+// CheckSyntax compiles input, reusing the binary for the runs that follow
+func (c *CExecutor) CheckSyntax(ctx context.Context, input Input) (ExecutionResult, error) {
+	_, failed, err := c.compile(ctx, input.Code, input.Modules)
+	if err != nil {
+		return ExecutionResult{}, err
+	}
+	if failed != nil {
+		return *failed, nil
+	}
+	return ExecutionResult{}, nil
+}
+
+// compile returns the path of the binary built from code and modules. When the compiler
+// rejects them, its result is returned instead with CompileError set.
+func (c *CExecutor) compile(ctx context.Context, code string, modules map[string]string) (string, *ExecutionResult, error) {
+	dir := c.CacheDir
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "python-runner", "c-cache")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", nil, fmt.Errorf("failed to create compile cache: %w", err)
+	}
+
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	hash := sha256.New()
+	hash.Write([]byte(code))
+	for _, name := range names {
+		fmt.Fprintf(hash, "\x00%s\x00%s", name, modules[name])
+	}
+	key := hex.EncodeToString(hash.Sum(nil))
+	binary := filepath.Join(dir, key)
+	if _, err := os.Stat(binary); err == nil {
+		// The modification time records the last use, see pruneCache
+		now := time.Now()
+		os.Chtimes(binary, now, now)
+		return binary, nil, nil
+	}
+
+	// The build runs in a workspace of its own, so headers resolve next to main.c and
+	// nothing of one submission's sources is left behind
+	sources := map[string]string{}
+	for name, content := range modules {
+		sources[name] = content
+	}
+	sources["main.c"] = code
+	args := []string{"-O2", "-std=c11", "-o", compiledName, "main.c"}
+	for _, name := range names {
+		if strings.HasSuffix(name, ".c") && name != "main.c" {
+			args = append(args, name)
+		}
+	}
+	args = append(args, "-lm")
+	result, err := runCommand(ctx, compileLimits(c.Limits), commandIO{files: sources, collect: []string{compiledName}}, "gcc", args...)
+	if err != nil {
+		return "", nil, err
+	}
+	if !result.Succeeded() {
+		result.CompileError = result.Limit == ""
+		result.OutputFiles = nil
+		return "", &result, nil
+	}
+	compiled, ok := result.OutputFiles[compiledName]
+	if !ok {
+		return "", nil, fmt.Errorf("gcc did not write a binary")
+	}
+	// Write next to the final path and rename, so concurrent compiles of the same
+	// submission never expose a half-written binary
+	output, err := os.CreateTemp(dir, key+".*.tmp")
+	if err != nil {
+		return "", nil, err
+	}
+	defer os.Remove(output.Name())
+	_, err = output.WriteString(compiled)
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(output.Name(), 0o755)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to write binary: %w", err)
+	}
+	if err := os.Rename(output.Name(), binary); err != nil {
+		return "", nil, err
+	}
+	pruneCache(dir, binary)
+	return binary, nil, nil
+}
+
+// pruneCache removes the files of the compile cache in dir not used for cacheMaxAge, then
+// the least recently used ones until the cache fits in cacheMaxBytes. keep, the binary just
+// built, and anything used within cacheMinAge stay.
+func pruneCache(dir string, keep string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	type cached struct {
+		path    string
+		size    int64
+		lastUse time.Time
+	}
+	var files []cached
+	var total int64
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, cached{filepath.Join(dir, entry.Name()), info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].lastUse.Before(files[j].lastUse) })
+
+	now := time.Now()
+	for _, f := range files {
+		age := now.Sub(f.lastUse)
+		if f.path == keep || age < cacheMinAge || (age < cacheMaxAge && total <= cacheMaxBytes) {
+			continue
+		}
+		if os.Remove(f.path) == nil {
+			total -= f.size
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package executer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultLanguage is the executor used for questions that do not name a language
+const DefaultLanguage = "python"
+
+// Input is one execution of a submission. Modules and Files are written to the working
+// directory next to the main source before the program starts; OutputFiles are read back
+// from it into ExecutionResult.OutputFiles once the program has finished.
+type Input struct {
+	Code        string            // the main source file
+	Modules     map[string]string // further source files of the submission, by file name
+	Stdin       string
+	Files       map[string]string // data files the program may read, by file name
+	OutputFiles []string          // files the program is expected to write
+
+	// peerIn and peerOut connect the program to the other side of an interactive run, see
+	// Interact; peerIn replaces Stdin and peerOut receives a copy of stdout
+	peerIn  *os.File
+	peerOut *os.File
+}
+
+// interactive reports whether the program talks to an interactor rather than reading Stdin
+func (input Input) interactive() bool {
+	return input.peerIn != nil
+}
+
+// commandIO is what runCommand needs of input, with files as the workspace files
+func (input Input) commandIO(files map[string]string) commandIO {
+	return commandIO{
+		stdin:   input.Stdin,
+		files:   files,
+		collect: input.OutputFiles,
+		peerIn:  input.peerIn,
+		peerOut: input.peerOut,
+	}
+}
+
+// workspaceFiles returns the files of input to write to the working directory, with the
+// main source stored as main when it is not empty. Data files never replace the sources.
+func (input Input) workspaceFiles(main string) map[string]string {
+	files := map[string]string{}
+	for name, content := range input.Files {
+		files[name] = content
+	}
+	for name, content := range input.Modules {
+		files[name] = content
+	}
+	if main != "" {
+		files[main] = input.Code
+	}
+	return files
+}
+
+// Executor runs submissions written in one language
+type Executor interface {
+	// Execute runs code with stdin. The error is only set when the program could not be
+	// run at all; how the program itself fared is described by the ExecutionResult.
+	Execute(ctx context.Context, code string, stdin string) (ExecutionResult, error)
+	// Run is Execute for a submission made of several files or a testcase with files
+	Run(ctx context.Context, input Input) (ExecutionResult, error)
+	// Version reports the version of the underlying interpreter or compiler
+	Version() (string, error)
+	// Name is the language name the executor is registered under
+	Name() string
+}
+
+// SyntaxChecker is implemented by executors that can reject a submission without running it
+type SyntaxChecker interface {
+	// CheckSyntax compiles the sources of input without running them. The result has
+	// CompileError set when they were rejected; Stdin and Files are not used.
+	CheckSyntax(ctx context.Context, input Input) (ExecutionResult, error)
+}
+
+// ExecutorFactory creates an executor that applies limits to every run
+type ExecutorFactory func(limits Limits) Executor
+
+var (
+	executorsMu sync.RWMutex
+	executors   = map[string]ExecutorFactory{}
+)
+
+func init() {
+	RegisterExecutor("python", func(limits Limits) Executor { return &PythonExecutor{Limits: limits} })
+	RegisterExecutor("c", func(limits Limits) Executor { return &CExecutor{Limits: limits} })
+	RegisterExecutor("node", func(limits Limits) Executor { return &NodeExecutor{Limits: limits} })
+}
+
+// RegisterExecutor makes an executor available under name, replacing any previous registration
+func RegisterExecutor(name string, factory ExecutorFactory) {
+	executorsMu.Lock()
+	defer executorsMu.Unlock()
+	executors[name] = factory
+}
+
+// ExecutorNames returns the registered language names in sorted order
+func ExecutorNames() []string {
+	executorsMu.RLock()
+	defer executorsMu.RUnlock()
+	names := make([]string, 0, len(executors))
+	for name := range executors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewExecutor creates the executor registered for language, or for DefaultLanguage when empty
+func NewExecutor(language string, limits Limits) (Executor, error) {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language == "" {
+		language = DefaultLanguage
+	}
+
+	executorsMu.RLock()
+	factory, ok := executors[language]
+	executorsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no executor for language %q", language)
+	}
+	return factory(limits), nil
+}
+
+// commandVersion runs a version command and returns the first line it prints
+func commandVersion(name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+
+	line, _, _ := strings.Cut(out.String(), "\n")
+	return line, nil
+}
@@ -0,0 +1,313 @@
+package executer
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestExecutors runs a small program through each registered language, skipping
+// languages whose toolchain is not installed
+func TestExecutors(t *testing.T) {
+	limits := Limits{AddressSpace: 256 << 20, CPUTime: 2 * time.Second, OpenFiles: 64, Output: 64 << 10}
+
+	cases := []struct {
+		language  string
+		tool      string
+		ok        string
+		syntaxErr string
+		crash     string
+	}{
+		{"python", "python3", "print(int(input()) * 2)", "print(", "raise SystemExit(3)"},
+		{"c", "gcc",
+			"#include <stdio.h>\nint main(void) { int n; scanf(\"%d\", &n); printf(\"%d\\n\", n * 2); return 0; }",
+			"int main(void) { return }",
+			"int main(void) { return 3; }"},
+		{"node", "node",
+			"const n = parseInt(require('fs').readFileSync(0, 'utf8')); console.log(n * 2);",
+			"for (",
+			"process.exit(3)"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.language, func(t *testing.T) {
+			if _, err := exec.LookPath(c.tool); err != nil {
+				t.Skipf("%s not installed", c.tool)
+			}
+			executor, err := NewExecutor(c.language, limits)
+			if err != nil {
+				t.Fatalf("NewExecutor: %v", err)
+			}
+			if cexec, ok := executor.(*CExecutor); ok {
+				cexec.CacheDir = t.TempDir()
+			}
+			if executor.Name() != c.language {
+				t.Fatalf("Name() = %q, want %q", executor.Name(), c.language)
+			}
+			if version, err := executor.Version(); err != nil || version == "" {
+				t.Fatalf("Version() = %q, %v", version, err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			result, err := executor.Execute(ctx, c.ok, "21\n")
+			if err != nil || !result.Succeeded() || strings.TrimSpace(result.Stdout) != "42" {
+				t.Fatalf("expected 42, got %+v (%v)", result, err)
+			}
+			// A second run of the same C source uses the cached binary
+			result, err = executor.Execute(ctx, c.ok, "5\n")
+			if err != nil || strings.TrimSpace(result.Stdout) != "10" {
+				t.Fatalf("expected 10 on second run, got %+v (%v)", result, err)
+			}
+
+			result, err = executor.Execute(ctx, c.syntaxErr, "")
+			if err != nil || !result.CompileError || result.Succeeded() {
+				t.Fatalf("expected a compile error, got %+v (%v)", result, err)
+			}
+
+			result, err = executor.Execute(ctx, c.crash, "")
+			if err != nil || result.CompileError || result.ExitCode != 3 {
+				t.Fatalf("expected exit code 3, got %+v (%v)", result, err)
+			}
+		})
+	}
+
+	if _, err := NewExecutor("cobol", limits); err == nil {
+		t.Fatalf("NewExecutor for an unknown language should fail")
+	}
+	if executor, err := NewExecutor("", limits); err != nil || executor.Name() != DefaultLanguage {
+		t.Fatalf("NewExecutor(\"\") should return the %s executor", DefaultLanguage)
+	}
+}
+
+// TestNodeExecutor_Script checks that JavaScript submissions run from a file: stack traces
+// point at main.js, sources larger than a single argument may be run, and a SyntaxError
+// thrown while running is not taken for a compile error
+func TestNodeExecutor_Script(t *testing.T) {
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node not installed")
+	}
+	executor := &NodeExecutor{}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := executor.Execute(ctx, "console.log(require('path').basename(__filename));\nnull.x;\n", "")
+	if err != nil || result.Stdout != "main.js\n" || !strings.Contains(result.Stderr, "main.js:2") {
+		t.Fatalf("unexpected stack trace: %+v (%v)", result, err)
+	}
+
+	// Linux refuses single arguments over 128 KB, which node -e used to run into
+	large := "const data = '" + strings.Repeat("x", 512<<10) + "';\nconsole.log(data.length);\n"
+	result, err = executor.Execute(ctx, large, "")
+	if err != nil || result.Stdout != "524288\n" {
+		t.Fatalf("large submission failed: %+v (%v)", result, err)
+	}
+
+	result, err = executor.Execute(ctx, "JSON.parse('{');", "")
+	if err != nil || result.CompileError || result.ExitCode != 1 {
+		t.Fatalf("expected a runtime error, got %+v (%v)", result, err)
+	}
+}
+
+// TestCExecutor_CachesBinary checks that compiling the same source twice reuses the binary
+func TestCExecutor_CachesBinary(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	executor := &CExecutor{CacheDir: t.TempDir()}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	first, failed, err := executor.compile(ctx, "int main(void) { return 0; }", nil)
+	if err != nil || failed != nil {
+		t.Fatalf("compile: %v %+v", err, failed)
+	}
+	info, err := os.Stat(first)
+	if err != nil {
+		t.Fatalf("stat binary: %v", err)
+	}
+	second, _, err := executor.compile(ctx, "int main(void) { return 0; }", nil)
+	if err != nil || second != first {
+		t.Fatalf("expected cached binary %s, got %s (%v)", first, second, err)
+	}
+	again, _ := os.Stat(second)
+	if !os.SameFile(info, again) {
+		t.Fatalf("cached binary was rebuilt")
+	}
+	if builds, _ := filepath.Glob(filepath.Join(executor.CacheDir, "*.src")); len(builds) > 0 {
+		t.Fatalf("build directories were left in the cache: %v", builds)
+	}
+}
+
+// TestPruneCache checks that the compile cache drops binaries unused for long and, over its
+// size bound, the least recently used ones, but never one in use
+func TestPruneCache(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	files := map[string]struct {
+		size    int
+		lastUse time.Duration
+	}{
+		"stale":  {1, 2 * cacheMaxAge},
+		"old":    {cacheMaxBytes / 2, 2 * time.Hour},
+		"recent": {cacheMaxBytes / 4, time.Hour},
+		"in-use": {cacheMaxBytes / 2, 0},
+		"kept":   {1, 3 * cacheMaxAge},
+	}
+	for name, f := range files {
+		// Sparse files have the size without taking the space
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Truncate(path, int64(f.size)); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, now.Add(-f.lastUse), now.Add(-f.lastUse))
+	}
+
+	pruneCache(dir, filepath.Join(dir, "kept"))
+	for name, wantKept := range map[string]bool{"stale": false, "old": false, "recent": true, "in-use": true, "kept": true} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if kept := err == nil; kept != wantKept {
+			t.Errorf("%s: kept %v, want %v", name, kept, wantKept)
+		}
+	}
+}
+
+// TestExecutors_Files runs a submission split over two files through each language. It
+// reads a testcase data file and writes an output file, which must come back in the result.
+func TestExecutors_Files(t *testing.T) {
+	limits := Limits{AddressSpace: 256 << 20, CPUTime: 2 * time.Second, OpenFiles: 64, Output: 64 << 10}
+
+	cases := []struct {
+		language string
+		tool     string
+		code     string
+		modules  map[string]string
+	}{
+		{"python", "python3",
+			"from helper import double\nn = int(open('data.txt').read())\nopen('out.txt', 'w').write(str(double(n)))\nprint('done')",
+			map[string]string{"helper.py": "def double(n):\n    return n * 2\n"}},
+		{"c", "gcc",
+			"#include <stdio.h>\n#include \"helper.h\"\nint main(void) { int n; FILE *in = fopen(\"data.txt\", \"r\"); fscanf(in, \"%d\", &n);\n" +
+				"FILE *out = fopen(\"out.txt\", \"w\"); fprintf(out, \"%d\", twice(n)); fclose(out); puts(\"done\"); return 0; }",
+			map[string]string{"helper.h": "int twice(int n);\n", "helper.c": "#include \"helper.h\"\nint twice(int n) { return n * 2; }\n"}},
+		{"node", "node",
+			"const { double } = require('./helper'); const fs = require('fs');\n" +
+				"fs.writeFileSync('out.txt', String(double(parseInt(fs.readFileSync('data.txt', 'utf8'))))); console.log('done');",
+			map[string]string{"helper.js": "exports.double = n => n * 2;\n"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.language, func(t *testing.T) {
+			if _, err := exec.LookPath(c.tool); err != nil {
+				t.Skipf("%s not installed", c.tool)
+			}
+			executor, err := NewExecutor(c.language, limits)
+			if err != nil {
+				t.Fatalf("NewExecutor: %v", err)
+			}
+			if cexec, ok := executor.(*CExecutor); ok {
+				cexec.CacheDir = t.TempDir()
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			result, err := executor.Run(ctx, Input{
+				Code:        c.code,
+				Modules:     c.modules,
+				Files:       map[string]string{"data.txt": "21\n"},
+				OutputFiles: []string{"out.txt", "missing.txt"},
+			})
+			if err != nil || !result.Succeeded() || result.Stdout != "done\n" {
+				t.Fatalf("unexpected result: %+v (%v)", result, err)
+			}
+			if len(result.OutputFiles) != 1 || result.OutputFiles["out.txt"] != "42" {
+				t.Fatalf("unexpected output files: %q", result.OutputFiles)
+			}
+		})
+	}
+}
+
+// TestPythonExecutor_OutputFileLink checks that an output file replaced by a link to a file
+// outside the working directory is not read back
+func TestPythonExecutor_OutputFileLink(t *testing.T) {
+	setUp()
+	secret := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(secret, []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := pythonExecutor.Run(ctx, Input{
+		Code:        "import os\nos.symlink(" + strconv.Quote(secret) + ", 'out.txt')",
+		OutputFiles: []string{"out.txt"},
+	})
+	if err != nil || !result.Succeeded() {
+		t.Fatalf("unexpected result: %+v (%v)", result, err)
+	}
+	if content, ok := result.OutputFiles["out.txt"]; ok {
+		t.Fatalf("linked output file was read: %q", content)
+	}
+}
+
+// TestExecutors_CheckSyntax checks that the languages that can check syntax on their own
+// reject broken sources, in the main file or a module, without running anything
+func TestExecutors_CheckSyntax(t *testing.T) {
+	limits := Limits{AddressSpace: 256 << 20, CPUTime: 2 * time.Second, OpenFiles: 64, Output: 64 << 10}
+
+	cases := []struct {
+		language  string
+		tool      string
+		ok        Input
+		broken    Input
+		errorText string
+	}{
+		{"python", "python3",
+			Input{Code: "from helper import double\nprint(double(1))\nraise SystemExit(3)", Modules: map[string]string{"helper.py": "def double(n):\n    return n * 2\n"}},
+			Input{Code: "from helper import double\nprint(double(1))", Modules: map[string]string{"helper.py": "def double(n)\n    return n * 2\n"}},
+			"SyntaxError"},
+		{"c", "gcc",
+			Input{Code: "#include <stdio.h>\nint main(void) { puts(\"ran\"); return 3; }"},
+			Input{Code: "int main(void) { return }"},
+			"error"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.language, func(t *testing.T) {
+			if _, err := exec.LookPath(c.tool); err != nil {
+				t.Skipf("%s not installed", c.tool)
+			}
+			executor, err := NewExecutor(c.language, limits)
+			if err != nil {
+				t.Fatalf("NewExecutor: %v", err)
+			}
+			if cexec, ok := executor.(*CExecutor); ok {
+				cexec.CacheDir = t.TempDir()
+			}
+			checker, ok := executor.(SyntaxChecker)
+			if !ok {
+				t.Fatalf("%s executor does not check syntax", c.language)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			result, err := checker.CheckSyntax(ctx, c.ok)
+			if err != nil || result.CompileError || result.Stdout != "" {
+				t.Fatalf("expected valid sources to pass without running, got %+v (%v)", result, err)
+			}
+			result, err = checker.CheckSyntax(ctx, c.broken)
+			if err != nil || !result.CompileError || result.Succeeded() || !strings.Contains(result.Stderr, c.errorText) {
+				t.Fatalf("expected a compile error, got %+v (%v)", result, err)
+			}
+		})
+	}
+}
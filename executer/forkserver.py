@@ -0,0 +1,265 @@
+# Fork server of a warm Python worker, see pool_linux.go. It imports the modules submissions
+# commonly use once and then forks a fresh copy of itself for every run it is sent on the
+# control socket, so each run starts from the same warm state without paying for the
+# interpreter startup. Each run is made in a new directory the grader creates for it. A run
+# request is a JSON object with the fds of the program's stdin, stdout and stderr attached:
+#
+#   {"dir": ..., "script": "main.py", "limits": {...}}
+#
+# Any message while the program runs kills it. Once it has finished, and everything it left
+# behind is gone, the server answers {"status": <wait status>, "utime": ..., "stime": ...,
+# "maxrss": ...}. It exits when the grader closes its end of the socket.
+import array
+import builtins
+import ctypes
+import gc
+import importlib
+import importlib.machinery
+import json
+import os
+import resource
+import select
+import shutil
+import signal
+import socket
+import stat
+import sys
+import types
+
+CONTROL_FD = 3
+PR_SET_DUMPABLE = 4
+
+# Imported before forking, so runs that use them find them loaded
+PRELOAD = [
+    "abc", "array", "bisect", "collections", "copy", "dataclasses", "datetime", "decimal",
+    "enum", "fractions", "functools", "heapq", "itertools", "json", "math", "operator",
+    "random", "re", "statistics", "string", "textwrap", "time", "typing",
+]
+
+# Limits of a run, in the order setLimits applies them for a fresh interpreter
+LIMITS = [
+    ("cpu", resource.RLIMIT_CPU),
+    ("nproc", resource.RLIMIT_NPROC),
+    ("fsize", resource.RLIMIT_FSIZE),
+    ("nofile", resource.RLIMIT_NOFILE),
+    ("as", resource.RLIMIT_AS),
+]
+
+
+def preload():
+    before = set(sys.modules)
+    for name in PRELOAD:
+        try:
+            importlib.import_module(name)
+        except Exception:
+            pass
+    return set(sys.modules) - before
+
+
+def set_dumpable(dumpable):
+    # A process that is not dumpable cannot be read or written through /proc by processes
+    # of the same user without CAP_SYS_PTRACE, which runs never have
+    ctypes.CDLL(None).prctl(PR_SET_DUMPABLE, int(dumpable), 0, 0, 0)
+
+
+def receive(control):
+    fds = array.array("i")
+    data, ancdata, _, _ = control.recvmsg(1 << 16, socket.CMSG_SPACE(3 * fds.itemsize))
+    for level, kind, payload in ancdata:
+        if level == socket.SOL_SOCKET and kind == socket.SCM_RIGHTS:
+            fds.frombytes(payload[:len(payload) - len(payload) % fds.itemsize])
+    return data, list(fds)
+
+
+def set_limits(limits):
+    resource.setrlimit(resource.RLIMIT_CORE, (0, 0))
+    for name, kind in LIMITS:
+        value = limits.get(name) or 0
+        if value > 0:
+            # The extra CPU second lets SIGXCPU arrive before the hard SIGKILL
+            resource.setrlimit(kind, (value, value + 1 if name == "cpu" else value))
+
+
+def run(directory, script, preloaded):
+    # Everything below happens in the forked child and ends with SystemExit, which unwinds
+    # to the top and shuts the interpreter down like a script that finished
+    path = os.path.join(directory, script)
+    # A module of the submission shadows a preloaded one, as it would in a fresh interpreter
+    local = set()
+    for name in os.listdir(directory):
+        local.add(name[:-3] if name.endswith(".py") else name)
+    for name in preloaded:
+        if name.split(".")[0] in local:
+            sys.modules.pop(name, None)
+    if "random" in sys.modules:
+        sys.modules["random"].seed()
+    if "tempfile" in sys.modules:
+        sys.modules["tempfile"].tempdir = None
+
+    sys.argv = [script]
+    sys.path[0] = directory
+    main = types.ModuleType("__main__")
+    main.__dict__.update(
+        __file__=path,
+        __cached__=None,
+        __builtins__=builtins,
+        __loader__=importlib.machinery.SourceFileLoader("__main__", path),
+        __annotations__={},
+    )
+    sys.modules["__main__"] = main
+
+    with open(path, "rb") as f:
+        source = f.read()
+    try:
+        code = compile(source, path, "exec", dont_inherit=True)
+    except (SyntaxError, ValueError) as error:
+        # Printed without a traceback, like the interpreter does
+        error.__traceback__ = None
+        sys.excepthook(type(error), error, None)
+        sys.exit(1)
+    try:
+        exec(code, main.__dict__)
+    except SystemExit:
+        raise
+    except BaseException as error:
+        # The traceback starts at the submission, leaving out this frame
+        error.__traceback__ = error.__traceback__.tb_next
+        sys.excepthook(type(error), error, error.__traceback__)
+        sys.exit(1)
+    sys.exit(0)
+
+
+def child(control, wakeup, request, fds, preloaded):
+    control.close()
+    signal.set_wakeup_fd(-1)
+    signal.signal(signal.SIGCHLD, signal.SIG_DFL)
+    for fd in wakeup:
+        os.close(fd)
+    os.setpgid(0, 0)
+    for target, fd in enumerate(fds):
+        os.dup2(fd, target)
+    for fd in fds:
+        if fd > 2:
+            os.close(fd)
+    # A sandboxed server has its home and temporary directory where the runs are made; each
+    # run gets its own directory for both
+    for name in ("HOME", "TMPDIR"):
+        if os.environ.get(name) == os.getcwd():
+            os.environ[name] = request["dir"]
+    os.chdir(request["dir"])
+    # The run is dumpable again, like a fresh interpreter, the server it came from is not
+    set_dumpable(True)
+    set_limits(request.get("limits") or {})
+    run(request["dir"], request["script"], preloaded)
+
+
+def wait(control, wakeup, pid):
+    while True:
+        done, status, usage = os.wait4(pid, os.WNOHANG)
+        if done:
+            return status, usage
+        readable, _, _ = select.select([control, wakeup[0]], [], [])
+        if wakeup[0] in readable:
+            os.read(wakeup[0], 512)
+        if control in readable:
+            closed = not control.recv(1 << 16)
+            try:
+                os.killpg(pid, signal.SIGKILL)
+            except ProcessLookupError:
+                pass
+            if closed:
+                os._exit(0)
+
+
+def remove(path):
+    if os.path.isdir(path) and not os.path.islink(path):
+        # The program may have taken away the permissions needed to remove what it left
+        for root, dirs, _ in os.walk(path):
+            for name in dirs:
+                try:
+                    os.chmod(os.path.join(root, name), stat.S_IRWXU)
+                except OSError:
+                    pass
+        shutil.rmtree(path, ignore_errors=True)
+    else:
+        try:
+            os.unlink(path)
+        except OSError:
+            pass
+
+
+def clear(directory, keep):
+    # Empties directory except for keep, the working directory the grader clears itself
+    for name in os.listdir(directory):
+        path = os.path.join(directory, name)
+        if path == keep:
+            continue
+        if keep.startswith(path + os.sep) and not os.path.islink(path):
+            clear(path, keep)
+        else:
+            remove(path)
+
+
+def clean_up(pid, directory):
+    if os.getpid() == 1:
+        # The server is the init of its own PID namespace: nothing else in it may survive
+        # the run, and its /tmp is private
+        try:
+            os.kill(-1, signal.SIGKILL)
+        except ProcessLookupError:
+            pass
+        while True:
+            try:
+                os.wait()
+            except ChildProcessError:
+                break
+        clear("/tmp", directory)
+    else:
+        try:
+            os.killpg(pid, signal.SIGKILL)
+        except ProcessLookupError:
+            pass
+
+
+def main():
+    control = socket.socket(fileno=CONTROL_FD)
+    # Runs share the server's user, and would otherwise be able to change the warm state
+    # the next run starts from through /proc/<pid>/mem
+    set_dumpable(False)
+    preloaded = preload()
+    wakeup = os.pipe()
+    for fd in wakeup:
+        os.set_blocking(fd, False)
+    signal.signal(signal.SIGCHLD, lambda number, frame: None)
+    signal.set_wakeup_fd(wakeup[1])
+    if hasattr(gc, "freeze"):
+        # Forked runs then share the preloaded objects instead of copying them
+        gc.freeze()
+    control.send(json.dumps({"ready": True}).encode())
+
+    while True:
+        data, fds = receive(control)
+        if not data:
+            return
+        request = json.loads(data)
+        if "dir" not in request:
+            # A kill that arrived after its run had already finished
+            for fd in fds:
+                os.close(fd)
+            continue
+        pid = os.fork()
+        if pid == 0:
+            child(control, wakeup, request, fds, preloaded)
+        for fd in fds:
+            os.close(fd)
+        status, usage = wait(control, wakeup, pid)
+        clean_up(pid, request["dir"])
+        control.send(json.dumps({
+            "status": status,
+            "utime": usage.ru_utime,
+            "stime": usage.ru_stime,
+            "maxrss": usage.ru_maxrss,
+        }).encode())
+
+
+main()
@@ -0,0 +1,91 @@
+package executer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// InteractorGrace is how long a program may keep running once its interactor has finished
+const InteractorGrace = time.Second
+
+// Interaction is the outcome of an interactive run
+type Interaction struct {
+	Program    ExecutionResult
+	Interactor ExecutionResult
+}
+
+// Interact runs a program against an interactor, with the stdout of each connected to the
+// stdin of the other; the Stdin of both inputs is ignored. Each side sees end of file once
+// the other has exited. A program that is still running InteractorGrace after the
+// interactor finished is stopped and reported as exceeding its time limit, so a program
+// waiting for input that never comes does not hold the grader until the deadline. When
+// both sides wait for each other, the deadline of ctx stops them both.
+func Interact(ctx context.Context, program Executor, input Input, interactor Executor, interactorInput Input) (Interaction, error) {
+	toInteractor, fromProgram, err := os.Pipe()
+	if err != nil {
+		return Interaction{}, err
+	}
+	toProgram, fromInteractor, err := os.Pipe()
+	if err != nil {
+		toInteractor.Close()
+		fromProgram.Close()
+		return Interaction{}, err
+	}
+	input.peerIn, input.peerOut = toProgram, fromProgram
+	interactorInput.peerIn, interactorInput.peerOut = toInteractor, fromInteractor
+
+	programCtx, stopProgram := context.WithCancel(ctx)
+	defer stopProgram()
+
+	var (
+		wg                      sync.WaitGroup
+		interaction             Interaction
+		programErr, interactErr error
+		programDone             = make(chan struct{})
+		interactorDone          = make(chan struct{})
+		stoppedAfterInteractor  bool
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		interaction.Program, programErr = program.Run(programCtx, input)
+		close(programDone)
+		// The interactor reads end of file and its writes fail from now on
+		fromProgram.Close()
+		toProgram.Close()
+	}()
+	go func() {
+		defer wg.Done()
+		interaction.Interactor, interactErr = interactor.Run(ctx, interactorInput)
+		close(interactorDone)
+		fromInteractor.Close()
+		toInteractor.Close()
+	}()
+
+	select {
+	case <-interactorDone:
+		select {
+		case <-time.After(InteractorGrace):
+			stoppedAfterInteractor = true
+			stopProgram()
+		case <-programDone:
+		}
+	case <-programDone:
+	}
+	wg.Wait()
+
+	if programErr != nil {
+		return Interaction{}, programErr
+	}
+	if interactErr != nil {
+		return Interaction{}, fmt.Errorf("interactor: %w", interactErr)
+	}
+	if stoppedAfterInteractor && !interaction.Program.Succeeded() && interaction.Program.Limit == "" {
+		interaction.Program.Limit = LimitTime
+		interaction.Program.LimitDetail = fmt.Sprintf("still running %s after the interactor finished", InteractorGrace)
+	}
+	return interaction, nil
+}
@@ -0,0 +1,99 @@
+package executer
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// guessInteractor answers guesses of a secret number with "<", ">" or "=" and records the
+// number of guesses it took in result.txt
+const guessInteractor = `
+import sys
+secret, guesses = 37, 0
+for line in sys.stdin:
+    guesses += 1
+    guess = int(line)
+    print("<" if secret < guess else ">" if secret > guess else "=")
+    if guess == secret:
+        break
+open("result.txt", "w").write(str(guesses))
+`
+
+// TestInteract connects programs to an interactor and checks the transcript and how runs
+// that stop talking end
+func TestInteract(t *testing.T) {
+	setUp()
+	cases := []struct {
+		name    string
+		program string
+		timeout time.Duration
+		check   func(t *testing.T, interaction Interaction)
+	}{
+		{"binary search", `
+lo, hi = 1, 100
+while True:
+    mid = (lo + hi) // 2
+    print(mid)
+    answer = input()
+    if answer == "=":
+        break
+    lo, hi = (mid + 1, hi) if answer == ">" else (lo, mid - 1)
+`, 5 * time.Second, func(t *testing.T, interaction Interaction) {
+			if !interaction.Program.Succeeded() || !interaction.Interactor.Succeeded() {
+				t.Fatalf("run failed: %+v", interaction)
+			}
+			if interaction.Interactor.OutputFiles["result.txt"] != "3" {
+				t.Fatalf("unexpected guesses %q", interaction.Interactor.OutputFiles["result.txt"])
+			}
+			if !strings.HasPrefix(interaction.Program.Stdout, "50\n25\n37\n") {
+				t.Fatalf("unexpected transcript %q", interaction.Program.Stdout)
+			}
+		}},
+		{"program gives up", "print(1)\n", 5 * time.Second, func(t *testing.T, interaction Interaction) {
+			if !interaction.Program.Succeeded() || !interaction.Interactor.Succeeded() {
+				t.Fatalf("run failed: %+v", interaction)
+			}
+			if interaction.Interactor.OutputFiles["result.txt"] != "1" {
+				t.Fatalf("interactor did not see the end of input: %+v", interaction.Interactor)
+			}
+		}},
+		{"program keeps waiting", "print(37)\ninput()\ninput()\n", 5 * time.Second, func(t *testing.T, interaction Interaction) {
+			if interaction.Program.Limit != "" || !interaction.Interactor.Succeeded() {
+				t.Fatalf("unexpected run: %+v", interaction)
+			}
+		}},
+		{"program never exits", "print(37)\ninput()\nimport time\ntime.sleep(60)\n", 10 * time.Second, func(t *testing.T, interaction Interaction) {
+			if interaction.Program.Limit != LimitTime || !strings.Contains(interaction.Program.LimitDetail, "after the interactor finished") {
+				t.Fatalf("expected the program to be stopped after the interactor, got %+v", interaction.Program)
+			}
+		}},
+		{"deadlock", "input()\n", 2 * time.Second, func(t *testing.T, interaction Interaction) {
+			if !interaction.Program.TimedOut || !interaction.Interactor.TimedOut {
+				t.Fatalf("expected both sides to time out, got %+v", interaction)
+			}
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			program := &PythonExecutor{Limits: pythonExecutor.Limits}
+			program.Limits.CPUTime = 0
+			interactor := &PythonExecutor{Limits: pythonExecutor.Limits}
+			interactor.Limits.CPUTime = 0
+			ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+			defer cancel()
+
+			start := time.Now()
+			interaction, err := Interact(ctx, program, Input{Code: c.program},
+				interactor, Input{Code: guessInteractor, OutputFiles: []string{"result.txt"}})
+			if err != nil {
+				t.Fatalf("interact: %v", err)
+			}
+			c.check(t, interaction)
+			if elapsed := time.Since(start); elapsed > c.timeout+2*time.Second {
+				t.Fatalf("interaction took %s", elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package executer
+
+import (
+	"fmt"
+	"strings"
+
+	"python-runner/configuration"
+)
+
+// Interpreter is a Python interpreter configured through PYTHON_INTERPRETERS
+type Interpreter struct {
+	Name string
+	Path string
+	// Version is the version the interpreter must report, e.g. "3.8"; empty accepts any
+	Version string
+}
+
+// Interpreters returns the configured Python interpreters
+func Interpreters() []Interpreter {
+	var interpreters []Interpreter
+	for _, i := range configuration.GetExecutorConfig().Interpreters {
+		interpreters = append(interpreters, Interpreter{Name: i.Name, Path: i.Path, Version: i.Version})
+	}
+	return interpreters
+}
+
+// LookupInterpreter returns the interpreter configured under name, or the default
+// interpreter when name is empty
+func LookupInterpreter(name string) (Interpreter, error) {
+	if name == "" {
+		name = configuration.GetExecutorConfig().DefaultInterpreter
+	}
+	for _, interpreter := range Interpreters() {
+		if interpreter.Name == name {
+			return interpreter, nil
+		}
+	}
+	return Interpreter{}, fmt.Errorf("python interpreter %q is not configured", name)
+}
+
+// Check verifies that the interpreter runs and reports the expected version
+func (i Interpreter) Check() error {
+	reported, err := commandVersion(i.Path, "--version")
+	if err != nil {
+		return fmt.Errorf("interpreter %s (%s) cannot be run: %w", i.Name, i.Path, err)
+	}
+	if !versionMatches(reported, i.Version) {
+		return fmt.Errorf("interpreter %s (%s) reports %q, expected version %s", i.Name, i.Path, reported, i.Version)
+	}
+	return nil
+}
+
+// CheckInterpreters verifies every configured interpreter, returning the first problem found
+func CheckInterpreters() error {
+	if _, err := LookupInterpreter(""); err != nil {
+		return fmt.Errorf("default %v", err)
+	}
+	for _, interpreter := range Interpreters() {
+		if err := interpreter.Check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// versionMatches reports whether a "Python X.Y.Z" version line satisfies the expected
+// version prefix, so "3.8" accepts 3.8.10 but not 3.80.
+func versionMatches(reported string, expected string) bool {
+	if expected == "" {
+		return true
+	}
+	version := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(reported), "Python"))
+	return version == expected || strings.HasPrefix(version, expected+".")
+}
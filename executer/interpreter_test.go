@@ -0,0 +1,46 @@
+package executer
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestVersionMatches checks expected version prefixes against reported versions
+func TestVersionMatches(t *testing.T) {
+	cases := []struct {
+		reported string
+		expected string
+		want     bool
+	}{
+		{"Python 3.8.10", "3.8", true},
+		{"Python 3.8.10\n", "3.8.10", true},
+		{"Python 3.80.1", "3.8", false},
+		{"Python 3.11.7", "3.10", false},
+		{"Python 3.11.7", "", true},
+	}
+	for _, c := range cases {
+		if got := versionMatches(c.reported, c.expected); got != c.want {
+			t.Errorf("versionMatches(%q, %q) = %v, want %v", c.reported, c.expected, got, c.want)
+		}
+	}
+}
+
+// TestInterpreter_Check runs python3 with a matching, a wrong and a missing interpreter
+func TestInterpreter_Check(t *testing.T) {
+	setUp()
+	reported, err := pythonExecutor.Version()
+	if err != nil {
+		t.Fatalf("Version error: %v", err)
+	}
+	version := strings.TrimSpace(strings.TrimPrefix(reported, "Python"))
+
+	if err := (Interpreter{Name: "py", Path: "python3", Version: version}).Check(); err != nil {
+		t.Fatalf("expected %s to match: %v", version, err)
+	}
+	if err := (Interpreter{Name: "py", Path: "python3", Version: "2.7"}).Check(); err == nil {
+		t.Fatalf("expected a version mismatch for 2.7")
+	}
+	if err := (Interpreter{Name: "missing", Path: "/nonexistent/python"}).Check(); err == nil {
+		t.Fatalf("expected an error for a missing interpreter")
+	}
+}
@@ -0,0 +1,139 @@
+package executer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"python-runner/configuration"
+)
+
+// Limits bounds the resources a single execution may use and whether it is isolated
+// from the grader. A zero field means no limit.
+type Limits struct {
+	AddressSpace int64         `json:"address_space"` // bytes of virtual memory (RLIMIT_AS)
+	CPUTime      time.Duration `json:"cpu_time"`      // CPU time (RLIMIT_CPU), rounded up to whole seconds
+	WallTime     time.Duration `json:"wall_time"`     // wall time from the start of the program, not counting a wait for a worker
+	Processes    int64         `json:"processes"`     // processes of the user (RLIMIT_NPROC), not enforced for root
+	FileSize     int64         `json:"file_size"`     // bytes written to any single file (RLIMIT_FSIZE)
+	OpenFiles    int64         `json:"open_files"`    // open file descriptors (RLIMIT_NOFILE)
+	Output       int64         `json:"output"`        // bytes captured from each of stdout and stderr
+	Sandbox      bool          `json:"sandbox"`       // run in a temporary directory, scrubbed environment and namespaces
+	// Seccomp restricts the system calls of the program; nil disables filtering
+	Seccomp *SeccompProfile `json:"seccomp,omitempty"`
+}
+
+// DefaultLimits returns the limits configured through the EXEC_* environment variables
+func DefaultLimits() Limits {
+	config := configuration.GetExecutorConfig()
+	profile, err := configuredSeccompProfile()
+	if err != nil {
+		log.Fatalf("Failed to load seccomp profile: %v", err)
+	}
+	return Limits{
+		AddressSpace: int64(config.MemoryLimitMB) << 20,
+		CPUTime:      time.Duration(config.CPUTimeLimitSeconds) * time.Second,
+		Processes:    int64(config.ProcessLimit),
+		FileSize:     int64(config.FileSizeLimitKB) << 10,
+		OpenFiles:    int64(config.OpenFilesLimit),
+		Output:       int64(config.OutputLimitKB) << 10,
+		Sandbox:      config.Sandbox,
+		Seccomp:      profile,
+	}
+}
+
+// IsZero reports whether no limit is set
+func (l Limits) IsZero() bool {
+	return l == Limits{}
+}
+
+// cpuSeconds is the CPU time limit in whole seconds, rounded up, as RLIMIT_CPU takes it
+func (l Limits) cpuSeconds() int64 {
+	if l.CPUTime <= 0 {
+		return 0
+	}
+	return int64((l.CPUTime + time.Second - 1) / time.Second)
+}
+
+// withWallTime bounds ctx by the wall time limit, counted from now on
+func (l Limits) withWallTime(ctx context.Context) (context.Context, context.CancelFunc) {
+	if l.WallTime <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, l.WallTime)
+}
+
+// hasRlimits reports whether any resource limit must be applied to the process itself
+// rather than enforced by the grader while reading its output.
+func (l Limits) hasRlimits() bool {
+	l.WallTime = 0
+	l.Output = 0
+	l.Sandbox = false
+	l.Seccomp = nil
+	return !l.IsZero()
+}
+
+// LimitKind classifies which limit stopped an execution
+type LimitKind string
+
+const (
+	LimitTime   LimitKind = "TLE"
+	LimitMemory LimitKind = "MLE"
+	LimitOutput LimitKind = "OLE"
+)
+
+func (k LimitKind) String() string {
+	switch k {
+	case LimitTime:
+		return "Time Limit Exceeded"
+	case LimitMemory:
+		return "Memory Limit Exceeded"
+	case LimitOutput:
+		return "Output Limit Exceeded"
+	default:
+		return string(k)
+	}
+}
+
+// LimitError is returned by Execute when the program was stopped by one of its limits
+type LimitError struct {
+	Kind   LimitKind
+	Detail string
+}
+
+func (e *LimitError) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("%s (%s)", e.Kind, string(e.Kind))
+	}
+	return fmt.Sprintf("%s (%s): %s", e.Kind, string(e.Kind), e.Detail)
+}
+
+// IsLimitError reports whether err was caused by an execution limit, returning its kind
+func IsLimitError(err error) (LimitKind, bool) {
+	var limitErr *LimitError
+	if errors.As(err, &limitErr) {
+		return limitErr.Kind, true
+	}
+	return "", false
+}
+
+// classifyLimit decides whether a failed run was stopped by a limit rather than by its own error.
+// It returns nil when the failure should be reported as an ordinary runtime error.
+func classifyLimit(ctx context.Context, result ExecutionResult, limits Limits) *LimitError {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &LimitError{Kind: LimitTime, Detail: "wall clock deadline reached"}
+	}
+	if kind, detail := signalLimit(result, limits); kind != "" {
+		return &LimitError{Kind: kind, Detail: detail}
+	}
+	if limits.AddressSpace > 0 && (strings.Contains(result.Stderr, "MemoryError") || strings.Contains(result.Stderr, "Cannot allocate memory")) {
+		return &LimitError{Kind: LimitMemory, Detail: fmt.Sprintf("address space limit of %d MB", limits.AddressSpace>>20)}
+	}
+	if limits.FileSize > 0 && strings.Contains(result.Stderr, "File too large") {
+		return &LimitError{Kind: LimitOutput, Detail: fmt.Sprintf("file size limit of %d KB", limits.FileSize>>10)}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package executer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// execSpecEnv carries the limits and sandbox from the grader to its re-executed helper
+// process. Go cannot run code between fork and exec, so the grader starts itself with this
+// variable set; the helper applies the spec to itself and then execs the real program.
+const execSpecEnv = "PYTHON_RUNNER_EXEC_SPEC"
+
+// helperExitCode is the exit status of a helper that failed before exec
+const helperExitCode = 125
+
+// execSpec is what the helper applies before exec. The helper itself is the grader and needs
+// its environment and working directory to start, so the program's are only applied at exec.
+type execSpec struct {
+	Limits  Limits       `json:"limits"`
+	Dir     string       `json:"dir,omitempty"`     // working directory of the program
+	Env     []string     `json:"env,omitempty"`     // environment of the program instead of the grader's
+	Sandbox *sandboxSpec `json:"sandbox,omitempty"` // set when the helper runs in new namespaces
+}
+
+func init() {
+	raw, ok := os.LookupEnv(execSpecEnv)
+	if !ok {
+		return
+	}
+	os.Unsetenv(execSpecEnv)
+	if err := execHelper(raw, os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "exec helper: %v\n", err)
+		os.Exit(helperExitCode)
+	}
+}
+
+// execHelper runs inside the re-executed grader: it applies the spec and replaces itself with argv
+func execHelper(raw string, argv []string) error {
+	// Capabilities, no_new_privs and seccomp filters belong to a thread, so everything
+	// up to exec has to happen on the same one
+	runtime.LockOSThread()
+
+	var spec execSpec
+	if err := json.Unmarshal([]byte(raw), &spec); err != nil {
+		return fmt.Errorf("invalid spec: %w", err)
+	}
+	if spec.Sandbox != nil {
+		if err := enterSandbox(spec.Sandbox); err != nil {
+			return err
+		}
+		if spec.Sandbox.Probe {
+			os.Exit(0)
+		}
+	}
+	if len(argv) == 0 {
+		return fmt.Errorf("no program to run")
+	}
+	if spec.Dir != "" {
+		if err := os.Chdir(spec.Dir); err != nil {
+			return err
+		}
+	}
+	env := os.Environ()
+	if spec.Env != nil {
+		env = spec.Env
+		os.Setenv("PATH", findEnv(env, "PATH"))
+	}
+	path, err := exec.LookPath(argv[0])
+	if err != nil {
+		return err
+	}
+
+	// Everything exec needs is converted up front: once the address space limit is in
+	// place the Go runtime itself may no longer be able to grow its heap.
+	pathPtr, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+	argvPtrs, err := syscall.SlicePtrFromStrings(argv)
+	if err != nil {
+		return err
+	}
+	envPtrs, err := syscall.SlicePtrFromStrings(env)
+	if err != nil {
+		return err
+	}
+
+	var filter []unix.SockFilter
+	if spec.Limits.Seccomp != nil {
+		if filter, err = spec.Limits.Seccomp.compile(); err != nil {
+			return err
+		}
+	}
+
+	if err := setLimits(spec.Limits); err != nil {
+		return err
+	}
+	if filter != nil {
+		if err := installSeccomp(filter); err != nil {
+			return err
+		}
+	}
+	_, _, errno := syscall.RawSyscall(syscall.SYS_EXECVE,
+		uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&argvPtrs[0])),
+		uintptr(unsafe.Pointer(&envPtrs[0])))
+	return fmt.Errorf("exec %s: %w", path, errno)
+}
+
+// setLimits applies limits to the current process so they are inherited across exec.
+// Soft and hard values are equal so the program cannot raise them again, except for
+// CPU time where the extra second lets SIGXCPU arrive before the hard SIGKILL.
+func setLimits(limits Limits) error {
+	type rlimit struct {
+		name     string
+		resource int
+		value    int64
+	}
+	// The address space limit goes last so the earlier calls are not starved of memory
+	rlimits := [...]rlimit{
+		{"core", unix.RLIMIT_CORE, 0},
+		{"cpu time", unix.RLIMIT_CPU, limits.cpuSeconds()},
+		{"processes", unix.RLIMIT_NPROC, limits.Processes},
+		{"file size", unix.RLIMIT_FSIZE, limits.FileSize},
+		{"open files", unix.RLIMIT_NOFILE, limits.OpenFiles},
+		{"address space", unix.RLIMIT_AS, limits.AddressSpace},
+	}
+
+	for _, r := range rlimits {
+		if r.value <= 0 && r.resource != unix.RLIMIT_CORE {
+			continue
+		}
+		value := syscall.Rlimit{Cur: uint64(r.value), Max: uint64(r.value)}
+		if r.resource == unix.RLIMIT_CPU {
+			value.Max++
+		}
+		if err := syscall.Setrlimit(r.resource, &value); err != nil {
+			return fmt.Errorf("failed to set %s limit: %w", r.name, err)
+		}
+	}
+	return nil
+}
+
+// limitedCommand returns a command running name with args under limits, in ws when it
+// is set. A sandboxed command gets a scrubbed environment and, when the kernel allows it,
+// new namespaces. Without limits the program is started directly.
+func limitedCommand(ctx context.Context, limits Limits, ws *workspace, name string, args ...string) (*exec.Cmd, error) {
+	if !limits.hasRlimits() && limits.Seccomp == nil && !limits.Sandbox {
+		cmd := exec.CommandContext(ctx, name, args...)
+		if ws != nil {
+			cmd.Dir = ws.Work
+		}
+		return cmd, nil
+	}
+
+	spec := execSpec{Limits: limits}
+	if ws != nil {
+		spec.Dir = ws.Work
+	}
+	if limits.Sandbox {
+		spec.Env = ws.env()
+		if sandboxSupported() {
+			spec.Sandbox = &sandboxSpec{Root: ws.Root, Work: ws.Work, ReadOnly: readOnlyPaths(name)}
+		}
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate grader executable: %w", err)
+	}
+	encoded, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, self, append([]string{name}, args...)...)
+	cmd.Env = append(os.Environ(), execSpecEnv+"="+string(encoded))
+	if spec.Sandbox != nil {
+		cmd.SysProcAttr = namespaceAttr()
+	}
+	return cmd, nil
+}
+
+// findEnv returns the value of key in an environment list
+func findEnv(env []string, key string) string {
+	for _, entry := range env {
+		if value, ok := strings.CutPrefix(entry, key+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// signalLimit maps the signal that terminated the program to the limit that raised it
+func signalLimit(result ExecutionResult, limits Limits) (LimitKind, string) {
+	switch result.Signal {
+	case unix.SignalName(syscall.SIGXCPU):
+		return LimitTime, fmt.Sprintf("cpu time limit of %s", limits.CPUTime)
+	case unix.SignalName(syscall.SIGXFSZ):
+		return LimitOutput, fmt.Sprintf("file size limit of %d KB", limits.FileSize>>10)
+	case unix.SignalName(syscall.SIGKILL):
+		// The kernel sends SIGKILL once the hard CPU limit is reached
+		if limits.CPUTime > 0 && result.CPUTime >= limits.CPUTime {
+			return LimitTime, fmt.Sprintf("cpu time limit of %s", limits.CPUTime)
+		}
+	}
+	return "", ""
+}
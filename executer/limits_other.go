@@ -0,0 +1,43 @@
+//go:build !linux
+
+package executer
+
+import (
+	"context"
+	"log"
+	"os/exec"
+	"sync"
+)
+
+var limitsWarning, sandboxWarning, seccompWarning sync.Once
+
+// limitedCommand returns a command running name with args. Resource limits, namespaces
+// and seccomp are only available on Linux; elsewhere the program runs with the context deadline alone
+// and a sandboxed command only gets its workspace and a scrubbed environment.
+func limitedCommand(ctx context.Context, limits Limits, ws *workspace, name string, args ...string) (*exec.Cmd, error) {
+	if limits.hasRlimits() {
+		limitsWarning.Do(func() {
+			log.Printf("Warning: execution resource limits are not supported on this platform")
+		})
+	}
+	if limits.Seccomp != nil {
+		seccompWarning.Do(func() {
+			log.Printf("Warning: seccomp filters are not supported on this platform")
+		})
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	if ws != nil {
+		cmd.Dir = ws.Work
+	}
+	if limits.Sandbox {
+		sandboxWarning.Do(func() {
+			log.Printf("Warning: namespaces are not supported on this platform, submissions run without file system and network isolation")
+		})
+		cmd.Env = ws.env()
+	}
+	return cmd, nil
+}
+
+func signalLimit(result ExecutionResult, limits Limits) (LimitKind, string) {
+	return "", ""
+}
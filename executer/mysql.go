@@ -2,6 +2,8 @@ package executer
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"python-runner/model"
 	"time"
 
@@ -45,6 +47,16 @@ func (e *MySQLExecuter) GetSourceCodeInfoWithContext(ctx context.Context, source
 	return sourceCode, nil
 }
 
+func (e *MySQLExecuter) GetQuestionWithContext(ctx context.Context, questionId int) (model.Question, error) {
+	var question model.Question
+	query := mysqlLocal.QuestionById
+	err := e.conn.GetContext(ctx, &question, query, questionId)
+	if err != nil {
+		return model.Question{}, err
+	}
+	return question, nil
+}
+
 func (e *MySQLExecuter) GetTestCases(questionId int) ([]model.Testcase, error) {
 	var testCases []model.Testcase
 	query := mysqlLocal.TestCasesByQuestionId
@@ -55,6 +67,7 @@ func (e *MySQLExecuter) GetTestCases(questionId int) ([]model.Testcase, error) {
 	return testCases, nil
 }
 
+// GetTestCasesWithContext returns the testcases of a question with their attached files
 func (e *MySQLExecuter) GetTestCasesWithContext(ctx context.Context, questionId int) ([]model.Testcase, error) {
 	var testCases []model.Testcase
 	query := mysqlLocal.TestCasesByQuestionId
@@ -62,6 +75,19 @@ func (e *MySQLExecuter) GetTestCasesWithContext(ctx context.Context, questionId
 	if err != nil {
 		return nil, err
 	}
+
+	var files []model.TestcaseFile
+	err = e.conn.SelectContext(ctx, &files, mysqlLocal.TestcaseFilesByQuestionId, questionId)
+	if err != nil {
+		return nil, err
+	}
+	byTestcase := map[int][]model.TestcaseFile{}
+	for _, f := range files {
+		byTestcase[f.TestcaseId] = append(byTestcase[f.TestcaseId], f)
+	}
+	for i := range testCases {
+		testCases[i].Files = byTestcase[testCases[i].TestcaseId]
+	}
 	return testCases, nil
 }
 
@@ -92,15 +118,51 @@ func (e *MySQLExecuter) InsertSourceCodeAtV2(newSourceCodeInfo model.SourceCode)
 	return newSourceCodeId, nil
 }
 
+// InsertSourceFilesV2 stores the further files of a multi-file submission
+func (e *MySQLExecuter) InsertSourceFilesV2(files []model.SourceFile) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	query := mysqlLocal.InsertSourceFileV2
+	for _, f := range files {
+		_, err := e.conn.ExecContext(ctx, query, f.StudentQuestionFileV2Id, f.FileName, f.Content)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (e *MySQLExecuter) InsertTestRunResultV2(testResult model.TestcaseResult) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	query := mysqlLocal.InsertTestRunResultV2
-	_, err := e.conn.ExecContext(ctx, query, testResult.StudentQuestionFileV2Id, testResult.TestcaseId, testResult.Score, testResult.Status, testResult.TestOutputText)
+	_, err := e.conn.ExecContext(ctx, query, testResult.StudentQuestionFileV2Id, testResult.TestcaseId, testResult.Score, testResult.Status, testResult.Verdict, testResult.TestOutputText, testResult.TimeLimitMs, testResult.MemoryLimitMB, testResult.WallTimeMs, testResult.CPUTimeMs, testResult.PeakMemoryKB)
 	return err
 }
 
+// InsertTestRunResultsV2 inserts the results of several testcases in one transaction, so
+// either all of them are recorded or none is
+func (e *MySQLExecuter) InsertTestRunResultsV2(testResults []model.TestcaseResult) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := e.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	query := mysqlLocal.InsertTestRunResultV2
+	for _, testResult := range testResults {
+		_, err := tx.ExecContext(ctx, query, testResult.StudentQuestionFileV2Id, testResult.TestcaseId, testResult.Score, testResult.Status, testResult.Verdict, testResult.TestOutputText, testResult.TimeLimitMs, testResult.MemoryLimitMB, testResult.WallTimeMs, testResult.CPUTimeMs, testResult.PeakMemoryKB)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
 func (e *MySQLExecuter) CalculateSourceCodeScoreV2(studentQuestionFileV2Id int, questionId int) (float32, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -108,6 +170,10 @@ func (e *MySQLExecuter) CalculateSourceCodeScoreV2(studentQuestionFileV2Id int,
 	var score float32
 	query := mysqlLocal.CalculateSourceCodeScoreV2
 	err := e.conn.QueryRowContext(ctx, query, studentQuestionFileV2Id, questionId).Scan(&score)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Every result was left ungraded
+		return 0, nil
+	}
 	if err != nil {
 		return 0, err
 	}
@@ -126,6 +192,7 @@ func (e *MySQLExecuter) UpdateSourceCodeAtV2(sourceCodeInfo model.SourceCode) er
 		sourceCodeInfo.SourceCode,
 		sourceCodeInfo.Version,
 		sourceCodeInfo.Score,
+		sourceCodeInfo.Verdict,
 		sourceCodeInfo.StudentQuestionFileV2Id,
 	)
 	return err
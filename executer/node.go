@@ -0,0 +1,60 @@
+package executer
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// nodeScript is the file the submission is written to, so stack traces show its name and
+// line numbers, and sources longer than a single argument run
+const nodeScript = "main.js"
+
+// nodeSourceFrame matches a stack frame inside a source file of the submission; node's own
+// modules have no .js extension. A SyntaxError without such a frame was raised while
+// parsing the submission rather than while running it.
+var nodeSourceFrame = regexp.MustCompile(`(?m)^\s+at .*\.js:\d+:\d+\)?$`)
+
+// NodeExecutor runs JavaScript submissions with node
+type NodeExecutor struct {
+	// Limits bounds each execution; the zero value runs without resource limits
+	Limits Limits
+}
+
+func (n *NodeExecutor) Name() string {
+	return "node"
+}
+
+func (n *NodeExecutor) Version() (string, error) {
+	return commandVersion("node", "--version")
+}
+
+func (n *NodeExecutor) Execute(ctx context.Context, code string, stdin string) (ExecutionResult, error) {
+	return n.Run(ctx, Input{Code: code, Stdin: stdin})
+}
+
+// Run executes input.Code as main.js with node in a working directory holding its modules,
+// which it can require as "./name". V8 reserves far more virtual memory than it uses, so the
+// address space limit is enforced as a V8 heap limit instead of RLIMIT_AS.
+func (n *NodeExecutor) Run(ctx context.Context, input Input) (ExecutionResult, error) {
+	limits := n.Limits
+	args := []string{}
+	if limits.AddressSpace > 0 {
+		args = append(args, fmt.Sprintf("--max-old-space-size=%d", limits.AddressSpace>>20))
+		limits.AddressSpace = 0
+	}
+	args = append(args, nodeScript)
+
+	result, err := runCommand(ctx, limits, input.commandIO(input.workspaceFiles(nodeScript)), "node", args...)
+	if err != nil {
+		return ExecutionResult{}, err
+	}
+	if result.Limit == "" && strings.Contains(result.Stderr, "JavaScript heap out of memory") {
+		result.Limit = LimitMemory
+		result.LimitDetail = fmt.Sprintf("heap limit of %d MB", n.Limits.AddressSpace>>20)
+	}
+	result.CompileError = result.ExitCode == 1 &&
+		strings.Contains(result.Stderr, "\nSyntaxError: ") && !nodeSourceFrame.MatchString(result.Stderr)
+	return result, nil
+}
@@ -0,0 +1,424 @@
+package executer
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// forkServer is the program of a warm worker, see forkserver.py
+//
+//go:embed forkserver.py
+var forkServer string
+
+const (
+	// workerStartTimeout bounds how long a new worker may take to import its modules
+	workerStartTimeout = 10 * time.Second
+	// workerKillTimeout bounds how long a worker may take to stop a run it was told to kill
+	workerKillTimeout = 5 * time.Second
+	// workerStderrLimit is how much of what a worker itself prints is kept for errors
+	workerStderrLimit = 64 << 10
+)
+
+// errWorkerFailed is returned when no worker could take a run or the worker broke during
+// it. The run has not been judged and is repeated by a fresh interpreter.
+var errWorkerFailed = errors.New("warm worker failed")
+
+// WorkerPool runs Python submissions on warm workers: fork servers that start the
+// interpreter and import common modules once, then fork a fresh copy of themselves for
+// each run. Every run starts from that same clean state in a working directory of its
+// own, which is removed before the next one along with the worker's /tmp and any process
+// left behind when it has namespaces of its own. Workers are started on demand, at most size of
+// them, and serve one run at a time. They run in the sandbox and under the seccomp profile
+// of the limits the pool was created with; the resource limits are applied to each run.
+type WorkerPool struct {
+	interpreter string
+	limits      Limits
+	idle        chan *pythonWorker
+	slots       chan struct{} // one for each worker that may still be started
+
+	mu     sync.Mutex
+	closed bool
+	broken error // why a worker could not be started; the pool takes no more runs
+}
+
+// NewWorkerPool creates a pool of at most size workers running interpreter, isolated as
+// limits ask. No worker is started until the first run.
+func NewWorkerPool(interpreter string, limits Limits, size int) *WorkerPool {
+	if size < 1 {
+		size = 1
+	}
+	pool := &WorkerPool{
+		interpreter: interpreter,
+		limits:      Limits{Sandbox: limits.Sandbox, Seccomp: limits.Seccomp},
+		idle:        make(chan *pythonWorker, size),
+		slots:       make(chan struct{}, size),
+	}
+	for i := 0; i < size; i++ {
+		pool.slots <- struct{}{}
+	}
+	return pool
+}
+
+// Close stops the idle workers and every busy one once its run has finished
+func (pool *WorkerPool) Close() {
+	pool.mu.Lock()
+	pool.closed = true
+	pool.mu.Unlock()
+	for {
+		select {
+		case worker := <-pool.idle:
+			pool.discard(worker)
+		default:
+			return
+		}
+	}
+}
+
+// serves reports whether the pool's workers can run for an executor with interpreter and
+// limits: the interpreter must be the same and the program isolated the same way
+func (pool *WorkerPool) serves(interpreter string, limits Limits) bool {
+	return interpreter == pool.interpreter && limits.Sandbox == pool.limits.Sandbox &&
+		reflect.DeepEqual(limits.Seccomp, pool.limits.Seccomp)
+}
+
+// run executes input as main.py under limits on a worker. The wall time limit starts once
+// a worker has been found, so waiting for one does not count against the run.
+func (pool *WorkerPool) run(ctx context.Context, limits Limits, input Input) (ExecutionResult, error) {
+	worker, err := pool.acquire(ctx)
+	if err != nil {
+		return ExecutionResult{}, err
+	}
+	runCtx, cancel := limits.withWallTime(ctx)
+	defer cancel()
+	result, err := worker.run(runCtx, limits, input.commandIO(input.workspaceFiles(pythonScript)))
+	if errors.Is(err, errWorkerFailed) {
+		pool.discard(worker)
+		return ExecutionResult{}, err
+	}
+	if resetErr := worker.reset(); resetErr != nil {
+		// What is left of this run must not be seen by the next one
+		pool.discard(worker)
+	} else {
+		pool.release(worker)
+	}
+	return result, err
+}
+
+// acquire returns an idle worker, starting one when there is none and the pool has room.
+// When ctx ends first the run is left to a fresh interpreter.
+func (pool *WorkerPool) acquire(ctx context.Context) (*pythonWorker, error) {
+	pool.mu.Lock()
+	closed, broken := pool.closed, pool.broken
+	pool.mu.Unlock()
+	if closed {
+		return nil, fmt.Errorf("%w: the pool is closed", errWorkerFailed)
+	}
+	if broken != nil {
+		return nil, fmt.Errorf("%w: %v", errWorkerFailed, broken)
+	}
+
+	select {
+	case worker := <-pool.idle:
+		return worker, nil
+	default:
+	}
+	select {
+	case worker := <-pool.idle:
+		return worker, nil
+	case <-pool.slots:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: no worker became free: %v", errWorkerFailed, ctx.Err())
+	}
+
+	worker, err := startWorker(pool.interpreter, pool.limits)
+	if err != nil {
+		pool.slots <- struct{}{}
+		pool.mu.Lock()
+		pool.broken = err
+		pool.mu.Unlock()
+		log.Printf("Warning: warm Python workers cannot be started (%v), submissions start a fresh interpreter", err)
+		return nil, fmt.Errorf("%w: %v", errWorkerFailed, err)
+	}
+	return worker, nil
+}
+
+// release makes a worker available to the next run
+func (pool *WorkerPool) release(worker *pythonWorker) {
+	pool.mu.Lock()
+	closed := pool.closed
+	pool.mu.Unlock()
+	if closed {
+		pool.discard(worker)
+		return
+	}
+	pool.idle <- worker
+}
+
+// discard stops a worker and frees its place in the pool
+func (pool *WorkerPool) discard(worker *pythonWorker) {
+	worker.stop()
+	pool.slots <- struct{}{}
+}
+
+// pythonWorker is one fork server of a pool, talking to the grader over a socket pair
+type pythonWorker struct {
+	cmd     *exec.Cmd
+	ws      *workspace
+	control *net.UnixConn
+	stderr  cappedBuffer
+	done    chan struct{} // closed once the server has exited
+	stopped sync.Once
+}
+
+// workerRequest asks a worker to run a program, see forkserver.py
+type workerRequest struct {
+	Dir    string       `json:"dir,omitempty"`
+	Script string       `json:"script,omitempty"`
+	Limits workerLimits `json:"limits"`
+	Kill   bool         `json:"kill,omitempty"`
+}
+
+// workerLimits are the resource limits of a run, applied by the forked program itself
+type workerLimits struct {
+	CPU          int64 `json:"cpu,omitempty"`
+	Processes    int64 `json:"nproc,omitempty"`
+	FileSize     int64 `json:"fsize,omitempty"`
+	OpenFiles    int64 `json:"nofile,omitempty"`
+	AddressSpace int64 `json:"as,omitempty"`
+}
+
+// workerStatus is how a run ended, as wait4 reported it to the worker
+type workerStatus struct {
+	Ready  bool    `json:"ready"`
+	Status int     `json:"status"`
+	UTime  float64 `json:"utime"`
+	STime  float64 `json:"stime"`
+	MaxRSS int64   `json:"maxrss"` // kilobytes
+}
+
+// startWorker starts a fork server and waits until it is ready for runs
+func startWorker(interpreter string, limits Limits) (*pythonWorker, error) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_SEQPACKET|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	local := os.NewFile(uintptr(fds[0]), "worker-control")
+	remote := os.NewFile(uintptr(fds[1]), "worker-control")
+	defer remote.Close()
+	conn, err := net.FileConn(local)
+	local.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	worker := &pythonWorker{
+		control: conn.(*net.UnixConn),
+		stderr:  cappedBuffer{limit: workerStderrLimit},
+		done:    make(chan struct{}),
+	}
+	if worker.ws, err = newWorkspace(); err != nil {
+		worker.control.Close()
+		return nil, err
+	}
+	program := (&PythonExecutor{Interpreter: interpreter, Limits: limits}).program()
+	cmd, err := limitedCommand(context.Background(), limits, worker.ws, program, "-c", forkServer)
+	if err != nil {
+		worker.control.Close()
+		worker.ws.remove()
+		return nil, err
+	}
+	setProcessGroup(cmd)
+	// The server finds its end of the socket as fd 3
+	cmd.ExtraFiles = []*os.File{remote}
+	cmd.Stderr = &worker.stderr
+	if err := cmd.Start(); err != nil {
+		worker.control.Close()
+		worker.ws.remove()
+		return nil, err
+	}
+	worker.cmd = cmd
+	go func() {
+		cmd.Wait()
+		close(worker.done)
+	}()
+
+	worker.control.SetReadDeadline(time.Now().Add(workerStartTimeout))
+	var ready workerStatus
+	if err := worker.receive(&ready); err != nil || !ready.Ready {
+		worker.stop()
+		if message := strings.TrimSpace(worker.stderr.String()); message != "" {
+			return nil, fmt.Errorf("worker did not start: %s", message)
+		}
+		return nil, fmt.Errorf("worker did not start: %v", err)
+	}
+	worker.control.SetReadDeadline(time.Time{})
+	return worker, nil
+}
+
+// run has the worker fork a program for one run and collects its result like runCommand
+func (w *pythonWorker) run(ctx context.Context, limits Limits, cio commandIO) (ExecutionResult, error) {
+	// runCtx is cancelled early when the program exceeds its output limit
+	runCtx, stopRun := context.WithCancel(ctx)
+	defer stopRun()
+
+	// A process an earlier run left behind cannot know where this one runs
+	dir, err := os.MkdirTemp(w.ws.Work, "run-")
+	if err != nil {
+		return ExecutionResult{}, err
+	}
+	if err := writeFiles(dir, cio.files); err != nil {
+		return ExecutionResult{}, err
+	}
+	request, err := json.Marshal(workerRequest{
+		Dir:    dir,
+		Script: pythonScript,
+		Limits: workerLimits{
+			CPU:          limits.cpuSeconds(),
+			Processes:    limits.Processes,
+			FileSize:     limits.FileSize,
+			OpenFiles:    limits.OpenFiles,
+			AddressSpace: limits.AddressSpace,
+		},
+	})
+	if err != nil {
+		return ExecutionResult{}, err
+	}
+
+	stdout, err := newOutputPipe(limits.Output, stopRun, cio.peerOut)
+	if err != nil {
+		return ExecutionResult{}, err
+	}
+	stderr, err := newOutputPipe(limits.Output, stopRun, nil)
+	if err != nil {
+		stdout.closeWriter()
+		stdout.wait()
+		return ExecutionResult{}, err
+	}
+	stdin, stdinWriter, err := os.Pipe()
+	if err != nil {
+		stdout.closeWriter()
+		stderr.closeWriter()
+		stdout.wait()
+		stderr.wait()
+		return ExecutionResult{}, err
+	}
+	go func() {
+		// Fails once the program has exited without reading everything
+		io.WriteString(stdinWriter, cio.stdin)
+		stdinWriter.Close()
+	}()
+
+	start := time.Now()
+	rights := unix.UnixRights(int(stdin.Fd()), int(stdout.writer.Fd()), int(stderr.writer.Fd()))
+	_, _, err = w.control.WriteMsgUnix(request, rights, nil)
+	// The worker holds its own copies now
+	stdin.Close()
+	stdout.closeWriter()
+	stderr.closeWriter()
+	if err != nil {
+		stdout.wait()
+		stderr.wait()
+		return ExecutionResult{}, fmt.Errorf("%w: %v", errWorkerFailed, err)
+	}
+
+	statuses := make(chan error, 1)
+	var status workerStatus
+	go func() {
+		statuses <- w.receive(&status)
+	}()
+	var receiveErr error
+	select {
+	case receiveErr = <-statuses:
+	case <-runCtx.Done():
+		kill, _ := json.Marshal(workerRequest{Kill: true})
+		w.control.Write(kill)
+		select {
+		case receiveErr = <-statuses:
+		case <-time.After(workerKillTimeout):
+			w.stop()
+			receiveErr = <-statuses
+		}
+	}
+	wallTime := time.Since(start)
+	outBuf := stdout.wait()
+	errBuf := stderr.wait()
+	if receiveErr != nil {
+		return ExecutionResult{}, fmt.Errorf("%w: %v", errWorkerFailed, receiveErr)
+	}
+
+	waitStatus := syscall.WaitStatus(status.Status)
+	result := ExecutionResult{
+		Stdout:          outBuf.String(),
+		Stderr:          errBuf.String(),
+		ExitCode:        waitStatus.ExitStatus(),
+		WallTime:        wallTime,
+		CPUTime:         time.Duration((status.UTime + status.STime) * float64(time.Second)),
+		PeakRSS:         status.MaxRSS << 10,
+		TimedOut:        errors.Is(ctx.Err(), context.DeadlineExceeded),
+		StdoutTruncated: outBuf.Truncated(),
+		StderrTruncated: errBuf.Truncated(),
+	}
+	if waitStatus.Signaled() {
+		result.Signal = unix.SignalName(waitStatus.Signal())
+	}
+	// Tracebacks name files by their full path; the workspace part means nothing to students
+	result.Stderr = strings.ReplaceAll(result.Stderr, dir+string(os.PathSeparator), "")
+	if result.OutputFiles, err = readFiles(dir, cio.collect); err != nil {
+		return ExecutionResult{}, err
+	}
+	markLimits(ctx, &result, limits)
+	return result, nil
+}
+
+// receive reads the next message of the worker into v
+func (w *pythonWorker) receive(v any) error {
+	buffer := make([]byte, 4096)
+	n, _, _, _, err := w.control.ReadMsgUnix(buffer, nil)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return io.EOF
+	}
+	return json.Unmarshal(buffer[:n], v)
+}
+
+// reset removes everything the last run left in the working directory
+func (w *pythonWorker) reset() error {
+	entries, err := os.ReadDir(w.ws.Work)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(w.ws.Work, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stop kills the worker, and any run in progress with it, and removes its workspace
+func (w *pythonWorker) stop() {
+	w.stopped.Do(func() {
+		w.control.Close()
+		killProcessGroup(w.cmd)
+		<-w.done
+		w.ws.remove()
+	})
+}
@@ -0,0 +1,279 @@
+package executer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// poolPrograms are run both by a fresh interpreter and on a warm worker, which must not be
+// told apart by anything the program or the grader sees
+var poolPrograms = []struct {
+	name  string
+	input Input
+}{
+	{"stdin", Input{Code: "print(int(input()) * 2)", Stdin: "21\n"}},
+	{"main", Input{Code: "import os, sys\nprint(__name__, os.path.basename(__file__), sys.argv, sys.path[0] == os.getcwd(), __spec__, __package__)"}},
+	{"exception", Input{Code: "def f():\n    raise ValueError('bad')\n\nf()"}},
+	{"syntax error", Input{Code: "print(\n"}},
+	{"exit code", Input{Code: "import sys\nprint('before')\nsys.exit(3)"}},
+	{"exit message", Input{Code: "raise SystemExit('stopped')"}},
+	{"shadowing module", Input{Code: "import random\nprint(random.value())", Modules: map[string]string{"random.py": "def value():\n    return 4\n"}}},
+	{"files", Input{Code: "open('out.txt', 'w').write(open('data.txt').read() * 2)", Files: map[string]string{"data.txt": "ab"}, OutputFiles: []string{"out.txt"}}},
+	{"thread", Input{Code: "import threading, time\nthreading.Thread(target=lambda: (time.sleep(0.1), print('late'))).start()\nprint('main')"}},
+	{"output limit", Input{Code: "print('x' * 200000)"}},
+	{"cpu limit", Input{Code: "while True:\n    pass"}},
+	{"memory limit", Input{Code: "data = bytearray(512 << 20)"}},
+}
+
+// TestWorkerPool checks that a run on a warm worker gives the result of a fresh interpreter
+func TestWorkerPool(t *testing.T) {
+	setUp()
+	for _, sandbox := range []bool{false, true} {
+		t.Run(fmt.Sprintf("sandbox=%v", sandbox), func(t *testing.T) {
+			spawn := &PythonExecutor{Limits: pythonExecutor.Limits}
+			spawn.Limits.Sandbox = sandbox
+			pool := NewWorkerPool(spawn.interpreter(), spawn.Limits, 2)
+			defer pool.Close()
+			warm := &PythonExecutor{Limits: spawn.Limits, Pool: pool}
+
+			for _, program := range poolPrograms {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				want, err := spawn.Run(ctx, program.input)
+				if err != nil {
+					t.Fatalf("%s: spawn: %v", program.name, err)
+				}
+				got, err := pool.run(ctx, warm.Limits, program.input)
+				cancel()
+				if err != nil {
+					t.Fatalf("%s: pool: %v", program.name, err)
+				}
+				got.CompileError = isPythonSyntaxError(got)
+				// A fresh interpreter in a sandbox is the init of its PID namespace, which does
+				// not die of SIGXCPU, so how a limit killed the program may differ
+				if got.Stdout != want.Stdout || got.Stderr != want.Stderr || got.ExitCode != want.ExitCode ||
+					(got.Signal != want.Signal && want.Limit == "") || got.Limit != want.Limit ||
+					got.CompileError != want.CompileError || !reflect.DeepEqual(got.OutputFiles, want.OutputFiles) {
+					t.Errorf("%s: warm run differs\n got %+v\nwant %+v", program.name, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestWorkerPool_Isolation checks that nothing of a run is left for the next one on the
+// same worker, and that a run stopped by its deadline does not break the worker
+func TestWorkerPool_Isolation(t *testing.T) {
+	setUp()
+	limits := pythonExecutor.Limits
+	limits.Sandbox = true
+	pool := NewWorkerPool("python3", limits, 1)
+	defer pool.Close()
+	executor := &PythonExecutor{Limits: limits, Pool: pool}
+
+	run := func(code string, timeout time.Duration) ExecutionResult {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		result, err := executor.Run(ctx, Input{Code: code})
+		if err != nil {
+			t.Fatalf("run: %v", err)
+		}
+		return result
+	}
+
+	first := run("import os, random, subprocess\n"+
+		"open('left.txt', 'w').write('x')\n"+
+		"open('/tmp/left.txt', 'w').write('x')\n"+
+		"subprocess.Popen(['sleep', '30'], start_new_session=True)\n"+
+		"print(os.getppid(), random.random(), os.getcwd())", 5*time.Second)
+	if !first.Succeeded() {
+		t.Fatalf("first run failed: %+v", first)
+	}
+
+	timedOut := run("import time\ntime.sleep(10)", time.Second)
+	if timedOut.Limit != LimitTime || !timedOut.TimedOut {
+		t.Fatalf("expected the deadline to stop the run, got %+v", timedOut)
+	}
+
+	second := run("import os, random\n"+
+		"print(os.getppid(), random.random(), os.getcwd())\n"+
+		"print(os.path.exists('left.txt'), os.path.exists('/tmp/left.txt'))\n"+
+		"print(sorted(p for p in os.listdir('/proc') if p.isdigit()) if os.getpid() < 100 else 'host')", 5*time.Second)
+	if !second.Succeeded() {
+		t.Fatalf("second run failed: %+v", second)
+	}
+	firstLines := strings.Fields(first.Stdout)
+	secondLines := strings.Split(strings.TrimSpace(second.Stdout), "\n")
+	server, seed := strings.Fields(secondLines[0])[0], strings.Fields(secondLines[0])[1]
+	if server != firstLines[0] {
+		t.Errorf("the runs were not served by the same worker: %s and %s", firstLines[0], server)
+	}
+	if seed == firstLines[1] {
+		t.Errorf("random gave the same number in both runs: %s", seed)
+	}
+	if cwd := strings.Fields(secondLines[0])[2]; cwd == firstLines[2] {
+		t.Errorf("both runs were made in the same directory: %s", cwd)
+	}
+	if !sandboxSupported() {
+		t.Log("namespaces unavailable, only checking the working directory")
+		if !strings.HasPrefix(secondLines[1], "False") {
+			t.Errorf("the working directory kept a file of the earlier run: %s", secondLines[1])
+		}
+		return
+	}
+	if secondLines[1] != "False False" {
+		t.Errorf("files of the earlier run were left: %s", secondLines[1])
+	}
+	// Only the worker and the run itself are left in the worker's namespace
+	if procs := strings.Count(secondLines[2], "'"); procs != 4 {
+		t.Errorf("processes of earlier runs survived: %s", secondLines[2])
+	}
+}
+
+// tamperServer overwrites a string preloaded by the fork server in the server's own memory,
+// where the run's copy sits at the same address
+const tamperServer = `
+import os, string
+text = string.ascii_lowercase.encode()
+with open("/proc/self/mem", "rb") as mem:
+    mem.seek(id(string.ascii_lowercase))
+    address = id(string.ascii_lowercase) + mem.read(256).index(text)
+try:
+    with open("/proc/%d/mem" % os.getppid(), "r+b", buffering=0) as mem:
+        mem.seek(address)
+        mem.write(b"pwned" * 5 + b"!")
+    print("written")
+except OSError as error:
+    print("denied", error.errno)
+`
+
+// TestWorkerPool_Tamper checks that a run cannot change the warm state of its fork server
+// that the next run on the same worker starts from
+func TestWorkerPool_Tamper(t *testing.T) {
+	setUp()
+	for _, sandbox := range []bool{false, true} {
+		t.Run(fmt.Sprintf("sandbox=%v", sandbox), func(t *testing.T) {
+			if !sandbox && os.Geteuid() == 0 {
+				t.Skip("runs of root outside the sandbox keep CAP_SYS_PTRACE, which reaches any process")
+			}
+			limits := pythonExecutor.Limits
+			limits.Sandbox = sandbox
+			pool := NewWorkerPool("python3", limits, 1)
+			defer pool.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			tamper, err := pool.run(ctx, limits, Input{Code: tamperServer})
+			if err != nil {
+				t.Fatalf("tampering run: %v", err)
+			}
+			result, err := pool.run(ctx, limits, Input{Code: "import string\nprint(string.ascii_lowercase)"})
+			if err != nil || result.Stdout != "abcdefghijklmnopqrstuvwxyz\n" {
+				t.Fatalf("the run after %q found a changed server: %+v (%v)", tamper.Stdout+tamper.Stderr, result, err)
+			}
+		})
+	}
+}
+
+// TestWorkerPool_Wait checks that the time a run waits for a busy worker does not count
+// against its wall time, and that a run whose context ends while waiting is left to a
+// fresh interpreter
+func TestWorkerPool_Wait(t *testing.T) {
+	setUp()
+	limits := pythonExecutor.Limits
+	limits.WallTime = 1500 * time.Millisecond
+	pool := NewWorkerPool("python3", limits, 1)
+	defer pool.Close()
+
+	busy := make(chan error, 1)
+	go func() {
+		result, err := pool.run(context.Background(), pythonExecutor.Limits, Input{Code: "import time\ntime.sleep(1.5)"})
+		if err == nil && !result.Succeeded() {
+			err = fmt.Errorf("busy run failed: %+v", result)
+		}
+		busy <- err
+	}()
+	// Let the first run take the only worker
+	time.Sleep(200 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := pool.run(ctx, limits, Input{Code: "print('ok')"}); !errors.Is(err, errWorkerFailed) {
+		t.Fatalf("expected a run that found no free worker to fail over, got %v", err)
+	}
+	result, err := pool.run(context.Background(), limits, Input{Code: "import time\ntime.sleep(0.8)\nprint('ok')"})
+	if err != nil || !result.Succeeded() || result.Stdout != "ok\n" {
+		t.Fatalf("the wait for the worker counted against the run: %+v (%v)", result, err)
+	}
+	if err := <-busy; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestWorkerPool_Fallback checks that a pool whose workers cannot start sends its runs
+// back to a fresh interpreter
+func TestWorkerPool_Fallback(t *testing.T) {
+	setUp()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	missing := NewWorkerPool("python-runner-missing-interpreter", pythonExecutor.Limits, 1)
+	defer missing.Close()
+	if _, err := missing.run(ctx, pythonExecutor.Limits, Input{Code: "print('ok')"}); !errors.Is(err, errWorkerFailed) {
+		t.Fatalf("expected the worker to fail, got %v", err)
+	}
+
+	pool := NewWorkerPool("python3", pythonExecutor.Limits, 1)
+	defer pool.Close()
+	pool.broken = errors.New("no worker")
+	executor := &PythonExecutor{Limits: pythonExecutor.Limits, Pool: pool}
+	result, err := executor.Run(ctx, Input{Code: "print('ok')"})
+	if err != nil || result.Stdout != "ok\n" {
+		t.Fatalf("unexpected result: %+v (%v)", result, err)
+	}
+}
+
+// benchmarkProgram is a small submission of the kind the grader runs thousands of times
+const benchmarkProgram = `
+import collections, math, re
+words = re.findall(r"\w+", input())
+counts = collections.Counter(words)
+print(max(counts.values()), math.isqrt(len(words)))
+`
+
+// BenchmarkPythonRun compares the throughput of starting an interpreter for every run
+// with that of warm workers, running as many programs at once as there are CPUs
+func BenchmarkPythonRun(b *testing.B) {
+	setUp()
+	input := Input{Code: benchmarkProgram, Stdin: strings.Repeat("a b c a ", 100) + "\n"}
+	for _, sandbox := range []bool{false, true} {
+		limits := pythonExecutor.Limits
+		limits.Sandbox = sandbox
+		b.Run(fmt.Sprintf("spawn/sandbox=%v", sandbox), func(b *testing.B) {
+			benchmarkRuns(b, &PythonExecutor{Limits: limits}, input)
+		})
+		b.Run(fmt.Sprintf("pool/sandbox=%v", sandbox), func(b *testing.B) {
+			pool := NewWorkerPool("python3", limits, runtime.GOMAXPROCS(0))
+			defer pool.Close()
+			benchmarkRuns(b, &PythonExecutor{Limits: limits, Pool: pool}, input)
+		})
+	}
+}
+
+func benchmarkRuns(b *testing.B, executor *PythonExecutor, input Input) {
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			result, err := executor.Run(context.Background(), input)
+			if err != nil || !result.Succeeded() {
+				b.Fatalf("run failed: %+v (%v)", result, err)
+			}
+		}
+	})
+}
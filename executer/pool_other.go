@@ -0,0 +1,38 @@
+//go:build !linux
+
+package executer
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+)
+
+var poolWarning sync.Once
+
+// errWorkerFailed sends every run back to a fresh interpreter
+var errWorkerFailed = errors.New("warm workers are not supported on this platform")
+
+// WorkerPool keeps warm Python workers on Linux. Elsewhere every run starts a fresh
+// interpreter, as it does without a pool.
+type WorkerPool struct{}
+
+// NewWorkerPool returns a pool that takes no runs on this platform
+func NewWorkerPool(interpreter string, limits Limits, size int) *WorkerPool {
+	poolWarning.Do(func() {
+		log.Printf("Warning: warm Python workers are not supported on this platform")
+	})
+	return &WorkerPool{}
+}
+
+// Close does nothing, the pool has no workers
+func (pool *WorkerPool) Close() {}
+
+func (pool *WorkerPool) serves(interpreter string, limits Limits) bool {
+	return false
+}
+
+func (pool *WorkerPool) run(ctx context.Context, limits Limits, input Input) (ExecutionResult, error) {
+	return ExecutionResult{}, errWorkerFailed
+}
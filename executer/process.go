@@ -0,0 +1,256 @@
+package executer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// pipeDrainTimeout bounds how long output is still read after the process group was killed.
+// It only matters when a descendant escaped the group and keeps the pipe open.
+const pipeDrainTimeout = time.Second
+
+// commandIO is what a command reads and what is collected from its workspace
+type commandIO struct {
+	stdin   string
+	files   map[string]string // written to the workspace before the command starts
+	collect []string          // read back from the workspace after it has finished
+	peerIn  *os.File          // read instead of stdin in an interactive run
+	peerOut *os.File          // receives a copy of stdout in an interactive run
+}
+
+// runCommand runs name with args under limits, feeding it stdin, and collects the result.
+// The program runs in its own process group so that a timeout or an exceeded output
+// limit also takes down anything it spawned. A program with files, or a sandboxed one,
+// runs in a fresh workspace where args can refer to the files by name; the workspace is
+// removed once the program has finished, however it ended.
+func runCommand(ctx context.Context, limits Limits, cio commandIO, name string, args ...string) (ExecutionResult, error) {
+	ctx, cancel := limits.withWallTime(ctx)
+	defer cancel()
+	// runCtx is cancelled early when the program exceeds its output limit
+	runCtx, stopRun := context.WithCancel(ctx)
+	defer stopRun()
+
+	var ws *workspace
+	if limits.Sandbox || len(cio.files) > 0 || len(cio.collect) > 0 {
+		var err error
+		if ws, err = newWorkspace(); err != nil {
+			return ExecutionResult{}, err
+		}
+		defer ws.remove()
+		if err := ws.writeFiles(cio.files); err != nil {
+			return ExecutionResult{}, err
+		}
+	}
+
+	cmd, err := limitedCommand(runCtx, limits, ws, name, args...)
+	if err != nil {
+		return ExecutionResult{}, err
+	}
+	setProcessGroup(cmd)
+	cmd.WaitDelay = pipeDrainTimeout
+
+	stdout, err := newOutputPipe(limits.Output, stopRun, cio.peerOut)
+	if err != nil {
+		return ExecutionResult{}, err
+	}
+	stderr, err := newOutputPipe(limits.Output, stopRun, nil)
+	if err != nil {
+		stdout.closeWriter()
+		stdout.wait()
+		return ExecutionResult{}, err
+	}
+	cmd.Stdout = stdout.writer
+	cmd.Stderr = stderr.writer
+	if cio.peerIn != nil {
+		cmd.Stdin = cio.peerIn
+	} else if cio.stdin != "" {
+		cmd.Stdin = bytes.NewBufferString(cio.stdin)
+	}
+
+	start := time.Now()
+	err = cmd.Start()
+	stdout.closeWriter()
+	stderr.closeWriter()
+	if err != nil {
+		stdout.wait()
+		stderr.wait()
+		return ExecutionResult{}, err
+	}
+
+	waitErr := cmd.Wait()
+	wallTime := time.Since(start)
+	// The program has exited; descendants it left behind are killed before reading the pipes
+	killProcessGroup(cmd)
+	outBuf := stdout.wait()
+	errBuf := stderr.wait()
+
+	var exitErr *exec.ExitError
+	if waitErr != nil && !errors.As(waitErr, &exitErr) {
+		return ExecutionResult{}, waitErr
+	}
+
+	state := cmd.ProcessState
+	result := ExecutionResult{
+		Stdout:          outBuf.String(),
+		Stderr:          errBuf.String(),
+		ExitCode:        state.ExitCode(),
+		Signal:          exitSignal(state),
+		WallTime:        wallTime,
+		CPUTime:         state.UserTime() + state.SystemTime(),
+		PeakRSS:         peakRSS(state),
+		TimedOut:        errors.Is(ctx.Err(), context.DeadlineExceeded),
+		StdoutTruncated: outBuf.Truncated(),
+		StderrTruncated: errBuf.Truncated(),
+	}
+
+	if ws != nil {
+		// Tracebacks name files by their full path; the workspace part means nothing to students
+		result.Stderr = strings.ReplaceAll(result.Stderr, ws.Work+string(os.PathSeparator), "")
+		if result.OutputFiles, err = ws.readFiles(cio.collect); err != nil {
+			return ExecutionResult{}, err
+		}
+	}
+
+	markLimits(ctx, &result, limits)
+	return result, nil
+}
+
+// markLimits records how the limits of a finished run stopped it, if they did
+func markLimits(ctx context.Context, result *ExecutionResult, limits Limits) {
+	// The filter kills with SIGSYS, which programs do not otherwise receive
+	result.SecurityViolation = limits.Seccomp != nil && result.Signal == "SIGSYS"
+
+	if result.StdoutTruncated || result.StderrTruncated {
+		result.Limit = LimitOutput
+		result.LimitDetail = fmt.Sprintf("output limit of %d KB", limits.Output>>10)
+	} else if result.ExitCode != 0 || result.Signal != "" {
+		if limitErr := classifyLimit(ctx, *result, limits); limitErr != nil {
+			result.Limit = limitErr.Kind
+			result.LimitDetail = limitErr.Detail
+		}
+	}
+}
+
+// outputPipe captures what a child writes to stdout or stderr. os/exec would otherwise
+// copy the output itself and make Wait block until every process holding the write end
+// exits, including orphaned descendants of the submission.
+type outputPipe struct {
+	reader *os.File
+	writer *os.File
+	buffer cappedBuffer
+	done   chan struct{}
+}
+
+// newOutputPipe captures at most limit bytes (0 for no limit) and calls onLimit once
+// when the program writes more than that. Everything is also forwarded to peer, if set,
+// until the peer goes away.
+func newOutputPipe(limit int64, onLimit func(), peer io.Writer) (*outputPipe, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	p := &outputPipe{
+		reader: r,
+		writer: w,
+		buffer: cappedBuffer{limit: limit, onLimit: onLimit},
+		done:   make(chan struct{}),
+	}
+	go func() {
+		if peer != nil {
+			io.Copy(&forwardingWriter{capture: &p.buffer, peer: peer}, r)
+		} else {
+			io.Copy(&p.buffer, r)
+		}
+		close(p.done)
+	}()
+	return p, nil
+}
+
+// forwardingWriter captures output and passes it on to the other side of an interactive
+// run. Once the peer stops reading, the output is only captured.
+type forwardingWriter struct {
+	capture io.Writer
+	peer    io.Writer
+}
+
+func (w *forwardingWriter) Write(p []byte) (int, error) {
+	if w.peer != nil {
+		if _, err := w.peer.Write(p); err != nil {
+			w.peer = nil
+		}
+	}
+	return w.capture.Write(p)
+}
+
+// closeWriter drops the parent's copy of the write end once the child has inherited it
+func (p *outputPipe) closeWriter() {
+	p.writer.Close()
+}
+
+// wait returns the captured output once every writer has closed the pipe, giving up
+// after pipeDrainTimeout so a surviving writer cannot block the grader.
+func (p *outputPipe) wait() *cappedBuffer {
+	select {
+	case <-p.done:
+	case <-time.After(pipeDrainTimeout):
+		p.reader.Close()
+		<-p.done
+	}
+	p.reader.Close()
+	return &p.buffer
+}
+
+// cappedBuffer keeps the first limit bytes written to it and discards the rest.
+// Writes never fail, so the pipe keeps draining until the program is stopped.
+type cappedBuffer struct {
+	buffer    bytes.Buffer
+	limit     int64
+	truncated bool
+	onLimit   func()
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.limit <= 0 {
+		return b.buffer.Write(p)
+	}
+	room := b.limit - int64(b.buffer.Len())
+	if int64(len(p)) <= room {
+		return b.buffer.Write(p)
+	}
+	if room > 0 {
+		b.buffer.Write(p[:room])
+	}
+	if !b.truncated {
+		b.truncated = true
+		if b.onLimit != nil {
+			b.onLimit()
+		}
+	}
+	return len(p), nil
+}
+
+// Truncated reports whether output was discarded
+func (b *cappedBuffer) Truncated() bool {
+	return b.truncated
+}
+
+// String returns the captured output, ending with a marker when it was truncated
+func (b *cappedBuffer) String() string {
+	if !b.truncated {
+		return b.buffer.String()
+	}
+	out := b.buffer.Bytes()
+	// Do not leave half of a multi-byte character before the marker
+	for i := 0; i < utf8.UTFMax && len(out) > 0 && !utf8.Valid(out); i++ {
+		out = out[:len(out)-1]
+	}
+	return fmt.Sprintf("%s\n...[output truncated after %d bytes]", out, b.limit)
+}
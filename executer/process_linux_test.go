@@ -0,0 +1,66 @@
+package executer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// findProcesses returns the pids whose command line is exactly args
+func findProcesses(t *testing.T, args ...string) []string {
+	t.Helper()
+	want := []byte{}
+	for _, a := range args {
+		want = append(append(want, a...), 0)
+	}
+
+	cmdlines, err := filepath.Glob("/proc/[0-9]*/cmdline")
+	if err != nil {
+		t.Fatalf("listing /proc: %v", err)
+	}
+	var pids []string
+	for _, path := range cmdlines {
+		cmdline, err := os.ReadFile(path)
+		if err == nil && bytes.Equal(cmdline, want) {
+			pids = append(pids, filepath.Base(filepath.Dir(path)))
+		}
+	}
+	return pids
+}
+
+// TestPythonExecutor_KillsDescendants runs fixtures that leave a child process behind, on normal
+// exit and on timeout, and checks that Execute returns promptly with no descendant still running.
+func TestPythonExecutor_KillsDescendants(t *testing.T) {
+	setUp()
+	dir := getFixturesDir(t)
+
+	cases := []struct {
+		fixture string
+		sleep   string
+	}{
+		{"ok_spawn_orphan", "313"},
+		{"err_spawn_timeout", "314"},
+	}
+
+	for _, c := range cases {
+		code, err := os.ReadFile(filepath.Join(dir, "testcode."+c.fixture+".py"))
+		if err != nil {
+			t.Fatalf("read code: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		start := time.Now()
+		result, execErr := pythonExecutor.Execute(ctx, string(code), "")
+		cancel()
+
+		if elapsed := time.Since(start); elapsed > 2*time.Second+2*pipeDrainTimeout {
+			t.Errorf("%s: Execute took %s, descendants kept it waiting", c.fixture, elapsed)
+		}
+		if pids := findProcesses(t, "sleep", c.sleep); len(pids) > 0 {
+			t.Errorf("%s: descendant sleep %s survived as pid %v (result: %+v, err: %v)", c.fixture, c.sleep, pids, result, execErr)
+		}
+	}
+}
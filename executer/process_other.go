@@ -0,0 +1,30 @@
+//go:build !linux
+
+package executer
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is a no-op where process groups are not supported; cancellation
+// falls back to killing the direct child only.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
+
+func exitSignal(state *os.ProcessState) string {
+	if state.ExitCode() == -1 {
+		return "killed"
+	}
+	return ""
+}
+
+func peakRSS(state *os.ProcessState) int64 {
+	return 0
+}
@@ -1,55 +1,149 @@
 package executer
 
 import (
-	"bytes"
 	"context"
 	"errors"
-	"os/exec"
+	"sort"
+	"strings"
+	"sync"
 )
 
-type PythonExecutor struct{}
+// pythonScript is the file the submission is written to, so tracebacks show its name,
+// line numbers and source lines, and __file__ is set
+const pythonScript = "main.py"
 
-func (p *PythonExecutor) Execute(ctx context.Context, code string, stdin string) (string, error) {
-	cmd := exec.CommandContext(ctx, "python3", "-c", code)
+// pythonExecutables caches the binary behind each interpreter command
+var pythonExecutables sync.Map
 
-	var outMsgBytes bytes.Buffer
-	var errMsgBytes bytes.Buffer
-	cmd.Stdout = &outMsgBytes
-	cmd.Stderr = &errMsgBytes
+// PythonExecutor runs Python submissions with python3
+type PythonExecutor struct {
+	// Limits bounds each execution; the zero value runs without resource limits
+	Limits Limits
+	// Interpreter is the python executable to run; empty uses python3 from PATH
+	Interpreter string
+	// Pool, when set, runs submissions on warm workers instead of starting the interpreter
+	// for each run. Interactive runs, and runs the pool cannot take, start it as before.
+	Pool *WorkerPool
+}
 
-	if stdin != "" {
-		cmd.Stdin = bytes.NewBufferString(stdin)
+func (p *PythonExecutor) interpreter() string {
+	if p.Interpreter == "" {
+		return "python3"
 	}
-	if err := cmd.Start(); err != nil {
-		return "", parseCodeError(&errMsgBytes, err)
+	return p.Interpreter
+}
+
+// executable returns the interpreter binary itself rather than a wrapper such as a pyenv
+// shim, which needs files outside the sandbox's read-only view of the interpreter.
+func (p *PythonExecutor) executable() string {
+	name := p.interpreter()
+	if path, ok := pythonExecutables.Load(name); ok {
+		return path.(string)
 	}
+	path, err := commandVersion(name, "-c", "import sys; print(sys.executable)")
+	if err != nil || path == "" {
+		// Running the command as given reports the problem to the caller
+		return name
+	}
+	pythonExecutables.Store(name, path)
+	return path
+}
 
-	if err := cmd.Wait(); err != nil {
-		return "", parseCodeError(&errMsgBytes, err)
+// program is what runs the interpreter: the binary itself in a sandbox, the command as
+// configured otherwise
+func (p *PythonExecutor) program() string {
+	if p.Limits.Sandbox {
+		return p.executable()
 	}
-	return outMsgBytes.String(), nil
+	return p.interpreter()
 }
 
-func parseCodeError(outputBytes *bytes.Buffer, err error) error {
-	output := outputBytes.String()
-	if len(output) == 0 {
-		return err
+// Execute runs code with stdin. The returned error is only set when the program could not
+// be run at all; how the program itself fared is described by the ExecutionResult.
+func (p *PythonExecutor) Execute(ctx context.Context, code string, stdin string) (ExecutionResult, error) {
+	return p.Run(ctx, Input{Code: code, Stdin: stdin})
+}
+
+// Run executes input.Code as main.py, with its modules importable from the same directory
+func (p *PythonExecutor) Run(ctx context.Context, input Input) (ExecutionResult, error) {
+	if p.Pool != nil && !input.interactive() && p.Pool.serves(p.interpreter(), p.Limits) {
+		result, err := p.Pool.run(ctx, p.Limits, input)
+		if !errors.Is(err, errWorkerFailed) {
+			if err == nil {
+				result.CompileError = isPythonSyntaxError(result)
+			}
+			return result, err
+		}
+		// A fresh interpreter runs what the worker could not
 	}
 
-	return errors.New(output)
+	program := p.program()
+	args := []string{pythonScript}
+	if input.interactive() {
+		// The other side waits for every line, so nothing may sit in a buffer
+		args = []string{"-u", pythonScript}
+	}
+	result, err := runCommand(ctx, p.Limits, input.commandIO(input.workspaceFiles(pythonScript)), program, args...)
+	if err != nil {
+		return ExecutionResult{}, err
+	}
+	result.CompileError = isPythonSyntaxError(result)
+	return result, nil
 }
 
-// check version of python
-func (p *PythonExecutor) Version() (string, error) {
-	cmd := exec.Command("python3", "--version")
+// pythonSyntaxCheck compiles the files named by its arguments, as py_compile would, and
+// prints the first SyntaxError the way the interpreter does for a script
+const pythonSyntaxCheck = `
+import sys, traceback
+for name in sys.argv[1:]:
+    try:
+        with open(name, "rb") as f:
+            compile(f.read(), name, "exec", dont_inherit=True)
+    except SyntaxError as error:
+        sys.stderr.write("".join(traceback.format_exception_only(type(error), error)))
+        sys.exit(1)
+`
 
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &out
+// CheckSyntax compiles main.py and the Python modules of input without running them
+func (p *PythonExecutor) CheckSyntax(ctx context.Context, input Input) (ExecutionResult, error) {
+	sources := Input{Code: input.Code, Modules: input.Modules}
+	names := []string{}
+	for name := range input.Modules {
+		if strings.HasSuffix(name, ".py") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	args := append([]string{"-c", pythonSyntaxCheck, pythonScript}, names...)
+	result, err := runCommand(ctx, p.Limits, sources.commandIO(sources.workspaceFiles(pythonScript)), p.program(), args...)
+	if err != nil {
+		return ExecutionResult{}, err
+	}
+	result.CompileError = isPythonSyntaxError(result)
+	return result, nil
+}
 
-	if err := cmd.Run(); err != nil {
-		return "", err
+// isPythonSyntaxError reports whether the interpreter rejected the code before running it.
+// Such errors are printed without the "Traceback" header of exceptions raised at run time.
+func isPythonSyntaxError(result ExecutionResult) bool {
+	if result.ExitCode != 1 || strings.Contains(result.Stderr, "Traceback (most recent call last)") {
+		return false
+	}
+	lines := strings.Split(strings.TrimSpace(result.Stderr), "\n")
+	last := lines[len(lines)-1]
+	for _, name := range []string{"SyntaxError", "IndentationError", "TabError"} {
+		if strings.HasPrefix(last, name) {
+			return true
+		}
 	}
+	return false
+}
+
+func (p *PythonExecutor) Name() string {
+	return "python"
+}
 
-	return out.String(), nil
+// check version of python
+func (p *PythonExecutor) Version() (string, error) {
+	return commandVersion(p.interpreter(), "--version")
 }
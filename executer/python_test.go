@@ -15,7 +15,15 @@ var pythonExecutor *PythonExecutor
 var testsuiteDir []string
 
 func setUp() {
-	pythonExecutor = &PythonExecutor{}
+	pythonExecutor = &PythonExecutor{
+		Limits: Limits{
+			AddressSpace: 256 << 20,
+			CPUTime:      time.Second,
+			FileSize:     1 << 20,
+			OpenFiles:    64,
+			Output:       64 << 10,
+		},
+	}
 	testsuiteDir = []string{
 		"err_exception",
 		"err_syntax",
@@ -128,17 +136,20 @@ func TestPythonExecutor_Fixtures(t *testing.T) {
 			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 			defer cancel()
 
-			got, execErr := pythonExecutor.Execute(ctx, string(codeBytes), stdinInput)
+			result, execErr := pythonExecutor.Execute(ctx, string(codeBytes), stdinInput)
+			if execErr != nil {
+				t.Fatalf("execute: %v", execErr)
+			}
 
-			if execErr != nil && got == "" {
-				errOut := normalize(execErr.Error())
+			if runErr := result.Err(); runErr != nil {
+				errOut := normalize(runErr.Error())
 				if !strings.Contains(errOut, want) {
 					t.Fatalf("error output mismatch\nwant substring:\n%s\n\ngot error:\n%s", want, errOut)
 				}
 				return
 			}
 
-			out := normalize(got)
+			out := normalize(result.Stdout)
 			if out != want {
 				t.Fatalf("output mismatch\nwant:\n%s\n\ngot:\n%s\n", showOutput(want), showOutput(out))
 			}
@@ -146,4 +157,119 @@ func TestPythonExecutor_Fixtures(t *testing.T) {
 	}
 }
 
+// TestPythonExecutor_OutputLimit checks that flooding stdout stops the program and returns
+// the truncated output with a marker alongside an output limit error
+func TestPythonExecutor_OutputLimit(t *testing.T) {
+	setUp()
+	code, err := os.ReadFile(filepath.Join(getFixturesDir(t), "testcode.err_output_flood.py"))
+	if err != nil {
+		t.Fatalf("read code: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	result, execErr := pythonExecutor.Execute(ctx, string(code), "")
+	if execErr != nil {
+		t.Fatalf("execute: %v", execErr)
+	}
+
+	if kind, ok := IsLimitError(result.Err()); !ok || kind != LimitOutput || !result.StdoutTruncated {
+		t.Fatalf("expected output limit error, got %v", result.Err())
+	}
+	got := result.Stdout
+	limit := int(pythonExecutor.Limits.Output)
+	if !strings.HasPrefix(got, "spam spam") || !strings.HasSuffix(got, "[output truncated after 65536 bytes]") {
+		t.Fatalf("unexpected truncated output: %q ... %q", got[:20], got[len(got)-50:])
+	}
+	if len(got) > limit+64 {
+		t.Fatalf("truncated output is %d bytes, limit is %d", len(got), limit)
+	}
+}
+
+// TestPythonExecutor_Result checks the fields of ExecutionResult for a crash with partial
+// output, syntax errors, a clean run and timeouts
+func TestPythonExecutor_Result(t *testing.T) {
+	setUp()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	result, err := pythonExecutor.Execute(ctx, "print('partial')\nraise SystemExit(3)", "")
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if result.Stdout != "partial\n" || result.ExitCode != 3 || result.Signal != "" || result.Succeeded() {
+		t.Fatalf("unexpected result for exit 3: %+v", result)
+	}
+	if result.WallTime <= 0 || result.PeakRSS <= 0 {
+		t.Fatalf("expected wall time and peak RSS to be measured: %+v", result)
+	}
+
+	result, err = pythonExecutor.Execute(ctx, "for i in range(3)\n    print(i)", "")
+	if err != nil || !result.CompileError {
+		t.Fatalf("expected a compile error for invalid syntax, got %+v (%v)", result, err)
+	}
+	result, err = pythonExecutor.Execute(ctx, "eval('1 +')", "")
+	if err != nil || result.CompileError || result.Succeeded() {
+		t.Fatalf("a SyntaxError raised at run time is not a compile error, got %+v (%v)", result, err)
+	}
+
+	result, err = pythonExecutor.Execute(ctx, "print('ok')", "")
+	if err != nil || !result.Succeeded() || result.Err() != nil {
+		t.Fatalf("expected success, got %+v (%v)", result, err)
+	}
+
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer shortCancel()
+	result, err = pythonExecutor.Execute(shortCtx, "import time\nprint('waiting', flush=True)\ntime.sleep(5)", "")
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if !result.TimedOut || result.Limit != LimitTime || result.Signal != "SIGKILL" || result.Stdout != "waiting\n" {
+		t.Fatalf("unexpected result for timeout: %+v", result)
+	}
+
+	// The wall time limit stops the run like a deadline of ctx
+	limited := &PythonExecutor{Limits: pythonExecutor.Limits}
+	limited.Limits.WallTime = 200 * time.Millisecond
+	result, err = limited.Execute(ctx, "import time\ntime.sleep(5)", "")
+	if err != nil || !result.TimedOut || result.Limit != LimitTime {
+		t.Fatalf("unexpected result for the wall time limit: %+v (%v)", result, err)
+	}
+}
+
+// TestPythonExecutor_Script checks that submissions run from a file: tracebacks point at
+// main.py with the offending source line, __file__ is set, sources larger than a single
+// argument may be run, and the workspace is gone after a timeout
+func TestPythonExecutor_Script(t *testing.T) {
+	setUp()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := pythonExecutor.Execute(ctx, "import os\nprint(os.path.basename(__file__))\nx = 1 / 0\n", "")
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if result.Stdout != "main.py\n" || !strings.Contains(result.Stderr, "File \"main.py\", line 3") ||
+		!strings.Contains(result.Stderr, "x = 1 / 0") {
+		t.Fatalf("unexpected traceback: %+v", result)
+	}
+
+	// Linux refuses single arguments over 128 KB, which python3 -c used to run into
+	large := "data = '" + strings.Repeat("x", 512<<10) + "'\nprint(len(data))\n"
+	result, err = pythonExecutor.Execute(ctx, large, "")
+	if err != nil || result.Stdout != "524288\n" {
+		t.Fatalf("large submission failed: %+v (%v)", result, err)
+	}
+
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer shortCancel()
+	result, err = pythonExecutor.Execute(shortCtx, "import os, time\nprint(os.getcwd(), flush=True)\ntime.sleep(5)", "")
+	if err != nil || !result.TimedOut {
+		t.Fatalf("expected a timeout, got %+v (%v)", result, err)
+	}
+	if _, err := os.Stat(strings.TrimSpace(result.Stdout)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("workspace %s was not removed: %v", strings.TrimSpace(result.Stdout), err)
+	}
+}
+
 // no extra helpers
@@ -0,0 +1,64 @@
+package executer
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ExecutionResult describes one finished run of a submission
+type ExecutionResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int    // -1 when the process was terminated by a signal
+	Signal   string // name of the terminating signal, empty for a normal exit
+
+	WallTime time.Duration
+	CPUTime  time.Duration // user + system time
+	PeakRSS  int64         // peak resident set size in bytes, 0 where unavailable
+
+	TimedOut        bool // the context deadline fired before the program finished
+	StdoutTruncated bool
+	StderrTruncated bool
+
+	// CompileError is set when the program was rejected before it started running,
+	// such as a Python SyntaxError
+	CompileError bool
+
+	// Limit is the limit that stopped the program, empty when none did
+	Limit       LimitKind
+	LimitDetail string
+
+	// SecurityViolation is set when the seccomp filter killed the program
+	SecurityViolation bool
+
+	// OutputFiles holds the Input.OutputFiles the program wrote, by file name
+	OutputFiles map[string]string
+}
+
+// Succeeded reports whether the program exited normally with status 0 within its limits
+func (r ExecutionResult) Succeeded() bool {
+	return r.ExitCode == 0 && r.Signal == "" && r.Limit == ""
+}
+
+// Err summarizes why the run failed, or returns nil when it succeeded. A run killed by the
+// seccomp filter yields ErrSecurityViolation and one stopped by a limit a *LimitError;
+// otherwise the error text is the program's stderr when it wrote any.
+func (r ExecutionResult) Err() error {
+	if r.SecurityViolation {
+		return ErrSecurityViolation
+	}
+	if r.Limit != "" {
+		return &LimitError{Kind: r.Limit, Detail: r.LimitDetail}
+	}
+	if r.Succeeded() {
+		return nil
+	}
+	if r.Stderr != "" {
+		return errors.New(r.Stderr)
+	}
+	if r.Signal != "" {
+		return fmt.Errorf("signal: %s", r.Signal)
+	}
+	return fmt.Errorf("exit status %d", r.ExitCode)
+}
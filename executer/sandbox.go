@@ -0,0 +1,112 @@
+package executer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// workspace is the scratch directory of one execution. The program runs in Work, which
+// holds its source files; when sandboxed, HOME and TMPDIR point there too and Root is an
+// empty mount point the Linux sandbox builds its minimal file system on.
+type workspace struct {
+	Dir  string
+	Work string
+	Root string
+}
+
+// newWorkspace creates a fresh workspace under the system temporary directory
+func newWorkspace() (*workspace, error) {
+	dir, err := os.MkdirTemp("", "python-runner-run-")
+	if err != nil {
+		return nil, err
+	}
+	ws := &workspace{
+		Dir:  dir,
+		Work: filepath.Join(dir, "work"),
+		Root: filepath.Join(dir, "root"),
+	}
+	for _, path := range []string{ws.Work, ws.Root} {
+		if err := os.Mkdir(path, 0o755); err != nil {
+			ws.remove()
+			return nil, err
+		}
+	}
+	return ws, nil
+}
+
+// writeFiles writes files, keyed by their name relative to the working directory, into it
+func (ws *workspace) writeFiles(files map[string]string) error {
+	return writeFiles(ws.Work, files)
+}
+
+// writeFiles writes files, keyed by their name relative to dir, creating subdirectories
+func writeFiles(dir string, files map[string]string) error {
+	for name, content := range files {
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("file name %q leaves the working directory", name)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readFiles returns the content of the named files the program left in the working
+// directory, skipping missing ones
+func (ws *workspace) readFiles(names []string) (map[string]string, error) {
+	return readFiles(ws.Work, names)
+}
+
+// readFiles returns the content of the named files in dir, skipping missing ones. Only
+// regular files inside it are read: the program may have replaced a name with a link to
+// something of the grader's.
+func readFiles(dir string, names []string) (map[string]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	work, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]string{}
+	for _, name := range names {
+		if !filepath.IsLocal(name) {
+			return nil, fmt.Errorf("file name %q leaves the working directory", name)
+		}
+		path := filepath.Join(work, name)
+		if real, err := filepath.EvalSymlinks(path); err != nil || real != path {
+			continue
+		}
+		if info, err := os.Lstat(path); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		files[name] = string(content)
+	}
+	return files, nil
+}
+
+// env returns the environment of a sandboxed program. Nothing is inherited from the
+// grader except PATH, so credentials such as MYSQL_PASSWORD never reach submissions.
+func (ws *workspace) env() []string {
+	return []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + ws.Work,
+		"TMPDIR=" + ws.Work,
+		"LANG=C.UTF-8",
+	}
+}
+
+// remove deletes the workspace and everything the program left in it
+func (ws *workspace) remove() {
+	os.RemoveAll(ws.Dir)
+}
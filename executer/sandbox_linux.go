@@ -0,0 +1,287 @@
+package executer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// sandboxSpec tells the helper which file system to build inside its namespaces
+type sandboxSpec struct {
+	Root     string   `json:"root"`      // empty directory the new root is mounted on
+	Work     string   `json:"work"`      // writable working directory, kept at the same path
+	ReadOnly []string `json:"read_only"` // host paths made visible read-only
+	Probe    bool     `json:"probe"`     // exit after entering the sandbox instead of exec
+}
+
+// systemPaths are the host directories every sandbox exposes read-only
+var systemPaths = []string{"/bin", "/sbin", "/lib", "/lib32", "/lib64", "/usr", "/etc"}
+
+// sandboxDevices are the only device nodes available inside the sandbox
+var sandboxDevices = []string{"/dev/null", "/dev/zero", "/dev/random", "/dev/urandom"}
+
+const sandboxCloneflags = syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID |
+	syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+
+var (
+	sandboxOnce      sync.Once
+	sandboxAvailable bool
+)
+
+// sandboxSupported reports whether programs can be started in new namespaces. It is
+// checked once by running the helper with an empty sandbox; when that fails a warning is
+// logged and sandboxed runs only get a temporary directory and a scrubbed environment.
+func sandboxSupported() bool {
+	sandboxOnce.Do(func() {
+		err := probeSandbox()
+		if err != nil {
+			log.Printf("Warning: namespaces are unavailable (%v), submissions run without file system and network isolation", err)
+		}
+		sandboxAvailable = err == nil
+	})
+	return sandboxAvailable
+}
+
+func probeSandbox() error {
+	ws, err := newWorkspace()
+	if err != nil {
+		return err
+	}
+	defer ws.remove()
+
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	spec, err := json.Marshal(execSpec{Sandbox: &sandboxSpec{Root: ws.Root, Work: ws.Work, Probe: true}})
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(self, "probe")
+	cmd.Env = append(os.Environ(), execSpecEnv+"="+string(spec))
+	cmd.SysProcAttr = namespaceAttr()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return errors.New(msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// namespaceAttr starts the helper in new namespaces, mapping the grader's user to root
+// inside them so the helper may mount its file system before dropping its capabilities.
+func namespaceAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{
+		Cloneflags:                 sandboxCloneflags,
+		UidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+		GidMappingsEnableSetgroups: false,
+	}
+}
+
+// readOnlyPaths returns the host paths a sandbox running program must see: the system
+// directories and the program itself, which may live elsewhere. A program in a bin
+// directory, like a pyenv interpreter, brings its whole installation; any other, like a
+// cached binary, only itself, so nothing next to it is visible.
+func readOnlyPaths(program string) []string {
+	paths := append([]string(nil), systemPaths...)
+	if path, err := exec.LookPath(program); err == nil {
+		if real, err := filepath.EvalSymlinks(path); err == nil {
+			path = real
+		}
+		if dir := filepath.Dir(path); filepath.Base(dir) == "bin" {
+			path = filepath.Dir(dir)
+		}
+		if abs, err := filepath.Abs(path); err == nil && abs != "/" {
+			paths = append(paths, abs)
+		}
+	}
+	return paths
+}
+
+// enterSandbox runs inside the helper's new namespaces. It builds a root file system on a
+// tmpfs holding read-only binds of the spec's paths, a few devices, a private /proc and
+// /tmp and the writable work directory, pivots into it and drops every capability.
+// The work directory keeps its host path, so the program's environment is the same
+// whether or not namespaces are available.
+func enterSandbox(spec *sandboxSpec) error {
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("failed to make mounts private: %w", err)
+	}
+	root := spec.Root
+	if err := unix.Mount("tmpfs", root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=0755,size=1m"); err != nil {
+		return fmt.Errorf("failed to mount sandbox root: %w", err)
+	}
+
+	// /tmp comes first: the work directory and cached binaries usually live below it
+	if err := os.MkdirAll(root+"/tmp", 0o1777); err != nil {
+		return err
+	}
+	if err := unix.Mount("tmpfs", root+"/tmp", "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777,size=16m"); err != nil {
+		return fmt.Errorf("failed to mount /tmp: %w", err)
+	}
+	for _, path := range outermostPaths(spec.ReadOnly) {
+		if err := bindReadOnly(root, path); err != nil {
+			return err
+		}
+	}
+	for _, device := range sandboxDevices {
+		if err := bindDevice(root, device); err != nil {
+			return err
+		}
+	}
+	// A private /proc only shows the sandbox's own processes. Mounting it is refused in
+	// some containers, and submissions rarely need it, so the sandbox does without.
+	if err := os.MkdirAll(root+"/proc", 0o555); err != nil {
+		return err
+	}
+	unix.Mount("proc", root+"/proc", "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "")
+	if err := os.MkdirAll(root+spec.Work, 0o755); err != nil {
+		return err
+	}
+	if err := unix.Mount(spec.Work, root+spec.Work, "", unix.MS_BIND, ""); err != nil {
+		return fmt.Errorf("failed to mount work directory: %w", err)
+	}
+
+	// pivot_root with the same old and new root stacks the old root on top, where it can
+	// be detached; unlike chroot this leaves nothing of the host to escape to.
+	if err := os.Chdir(root); err != nil {
+		return err
+	}
+	if err := unix.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("failed to pivot into sandbox: %w", err)
+	}
+	if err := unix.Unmount(".", unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("failed to detach host root: %w", err)
+	}
+	if err := unix.Mount("", "/", "", unix.MS_REMOUNT|unix.MS_BIND|unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("failed to make sandbox root read-only: %w", err)
+	}
+	if err := os.Chdir("/"); err != nil {
+		return err
+	}
+	unix.Sethostname([]byte("sandbox"))
+	return dropCapabilities()
+}
+
+// outermostPaths returns paths sorted, leaving out any path already visible through
+// another one, such as an interpreter installed under /usr
+func outermostPaths(paths []string) []string {
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+	var result []string
+	for _, path := range sorted {
+		covered := false
+		for _, parent := range result {
+			if path == parent || strings.HasPrefix(path, parent+"/") {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			result = append(result, path)
+		}
+	}
+	return result
+}
+
+// bindReadOnly makes the host path visible at the same place under root. Symbolic links,
+// like /bin on merged-/usr systems, are recreated instead of followed.
+func bindReadOnly(root, path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	target := root + path
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		link, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := os.Symlink(link, target); err != nil && !errors.Is(err, os.ErrExist) {
+			return err
+		}
+		return nil
+	}
+
+	if info.IsDir() {
+		err = os.MkdirAll(target, 0o755)
+	} else if err = os.MkdirAll(filepath.Dir(target), 0o755); err == nil {
+		err = createFile(target)
+	}
+	if err != nil {
+		return err
+	}
+	if err := unix.Mount(path, target, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("failed to bind %s: %w", path, err)
+	}
+	// Flags the host mount is locked with must be kept, or the remount is refused
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return err
+	}
+	locked := uintptr(stat.Flags) & (unix.MS_NOEXEC | unix.MS_NOATIME | unix.MS_NODIRATIME | unix.MS_RELATIME)
+	flags := unix.MS_REMOUNT | unix.MS_BIND | unix.MS_RDONLY | unix.MS_NOSUID | unix.MS_NODEV | locked
+	if err := unix.Mount("", target, "", flags, ""); err != nil {
+		return fmt.Errorf("failed to make %s read-only: %w", path, err)
+	}
+	return nil
+}
+
+// bindDevice makes a host device node available under root
+func bindDevice(root, device string) error {
+	target := root + device
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	if err := createFile(target); err != nil {
+		return err
+	}
+	if err := unix.Mount(device, target, "", unix.MS_BIND, ""); err != nil {
+		return fmt.Errorf("failed to bind %s: %w", device, err)
+	}
+	return nil
+}
+
+func createFile(path string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// dropCapabilities empties the bounding set so the program, although root inside the
+// user namespace, gains no capabilities when the helper execs it
+func dropCapabilities() error {
+	for capability := 0; ; capability++ {
+		err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(capability), 0, 0, 0)
+		if errors.Is(err, unix.EINVAL) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to drop capabilities: %w", err)
+		}
+	}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to set no_new_privs: %w", err)
+	}
+	return nil
+}
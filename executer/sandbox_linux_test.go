@@ -0,0 +1,131 @@
+package executer
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sandboxProbe prints what a submission can see of its surroundings, one fact per line
+const sandboxProbe = `
+import os, socket
+print("cwd", os.getcwd() == os.environ.get("HOME"))
+print("password", "MYSQL_PASSWORD" in os.environ)
+open("scratch.txt", "w").write("ok")
+print("scratch", open("scratch.txt").read())
+for name, path in [("grader", GRADER_FILE), ("usr", "/usr/sandbox-write-test")]:
+    try:
+        open(path, "a").close()
+        print(name, "writable")
+    except OSError:
+        print(name, "denied")
+try:
+    socket.create_connection(("127.0.0.1", 9), timeout=1)
+    print("network open")
+except OSError:
+    print("network denied")
+print("pid", os.getpid())
+`
+
+// TestPythonExecutor_Sandbox checks that a sandboxed submission runs in its own temporary
+// directory without the grader's environment and, when namespaces are available, without
+// access to the grader's files, the network or the rest of the file system.
+func TestPythonExecutor_Sandbox(t *testing.T) {
+	setUp()
+	t.Setenv("MYSQL_PASSWORD", "secret")
+	graderFile, err := filepath.Abs("python_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	executor := &PythonExecutor{Limits: pythonExecutor.Limits}
+	executor.Limits.Sandbox = true
+	code := strings.Replace(sandboxProbe, "GRADER_FILE", "'"+graderFile+"'", 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := executor.Execute(ctx, code, "")
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if !result.Succeeded() {
+		t.Fatalf("sandboxed run failed: %+v", result)
+	}
+
+	want := []string{"cwd True", "password False", "scratch ok"}
+	if sandboxSupported() {
+		want = append(want, "grader denied", "usr denied", "network denied", "pid 1")
+	} else {
+		t.Log("namespaces unavailable, only checking the workspace and environment")
+	}
+	for _, line := range want {
+		if !strings.Contains(result.Stdout, line+"\n") {
+			t.Errorf("expected %q in output:\n%s", line, result.Stdout)
+		}
+	}
+
+	leftovers, _ := filepath.Glob(filepath.Join(os.TempDir(), "python-runner-run-*"))
+	if len(leftovers) > 0 {
+		t.Errorf("workspaces were not removed: %v", leftovers)
+	}
+}
+
+// TestCExecutor_SandboxHidesCache checks that a sandboxed C program sees its own binary but
+// nothing else of the shared compile cache
+func TestCExecutor_SandboxHidesCache(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	if !sandboxSupported() {
+		t.Skip("namespaces unavailable")
+	}
+	cache := t.TempDir()
+	secret := filepath.Join(cache, "other-submission")
+	if err := os.WriteFile(secret, []byte("binary"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	executor := &CExecutor{Limits: Limits{Sandbox: true}, CacheDir: cache}
+	code := `#include <stdio.h>
+int main(void) {
+	puts(fopen("` + secret + `", "r") ? "visible" : "hidden");
+	return 0;
+}`
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, err := executor.Run(ctx, Input{Code: code})
+	if err != nil || !result.Succeeded() {
+		t.Fatalf("run failed: %+v (%v)", result, err)
+	}
+	if result.Stdout != "hidden\n" {
+		t.Errorf("the sandbox exposed the compile cache: %q", result.Stdout)
+	}
+}
+
+// TestCExecutor_SandboxedCompile checks that the compiler cannot read a host file the
+// source includes, so its diagnostics never quote the grader's secrets
+func TestCExecutor_SandboxedCompile(t *testing.T) {
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc not installed")
+	}
+	if !sandboxSupported() {
+		t.Skip("namespaces unavailable")
+	}
+	secret := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(secret, []byte("MYSQL_PASSWORD=hunter2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	executor := &CExecutor{CacheDir: t.TempDir()}
+	code := "#include \"" + secret + "\"\nint main(void) { return 0; }"
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, err := executor.Run(ctx, Input{Code: code})
+	if err != nil || !result.CompileError {
+		t.Fatalf("expected a compile error, got %+v (%v)", result, err)
+	}
+	if strings.Contains(result.Stdout+result.Stderr, "hunter2") {
+		t.Errorf("the compiler leaked the included file: %q", result.Stderr)
+	}
+}
@@ -0,0 +1,117 @@
+package executer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"python-runner/configuration"
+)
+
+// ErrSecurityViolation is reported for a program killed by its seccomp filter
+var ErrSecurityViolation = errors.New("Security Violation: the program made a forbidden system call")
+
+// SeccompProfile selects the system calls a submission may make. A call listed in Deny
+// kills the program, one listed in Unavailable fails, one listed in Allow is permitted,
+// and any other call gets the default action. Profile files hold the same fields as JSON.
+type SeccompProfile struct {
+	// DefaultAction is "allow" or "kill"
+	DefaultAction string   `json:"default_action"`
+	Allow         []string `json:"allow,omitempty"`
+	Deny          []string `json:"deny,omitempty"`
+	// Unavailable calls fail with ENOSYS, as on a kernel without them, so libraries fall
+	// back to older calls the other rules can check, like clone3 to clone
+	Unavailable []string `json:"unavailable,omitempty"`
+	// DenyNamespaces kills clone calls that create namespaces, which would get around
+	// unshare and setns in Deny
+	DenyNamespaces bool `json:"deny_namespaces,omitempty"`
+	// SocketFamilies restricts socket(2) to these address families, e.g. "AF_UNIX".
+	// Empty leaves socket to the rules above.
+	SocketFamilies []string `json:"socket_families,omitempty"`
+}
+
+// DefaultSeccompProfile allows everything except calls that reach outside the program:
+// tracing or reading other processes, mounting, loading kernel code, changing namespaces
+// or system settings, and sockets other than AF_UNIX. io_uring is denied as it makes
+// calls, sockets among them, the filter never sees, and clone3 is unavailable as its flags
+// cannot be checked.
+func DefaultSeccompProfile() *SeccompProfile {
+	return &SeccompProfile{
+		DefaultAction: "allow",
+		Deny: []string{
+			"ptrace", "process_vm_readv", "process_vm_writev",
+			"mount", "umount2", "pivot_root", "chroot",
+			"kexec_load", "kexec_file_load", "init_module", "finit_module", "delete_module",
+			"bpf", "perf_event_open", "userfaultfd",
+			"unshare", "setns",
+			"keyctl", "add_key", "request_key",
+			"open_by_handle_at", "name_to_handle_at",
+			"reboot", "swapon", "swapoff", "acct", "quotactl",
+			"settimeofday", "clock_settime", "clock_adjtime", "adjtimex",
+			"sethostname", "setdomainname",
+			"io_uring_setup", "io_uring_enter", "io_uring_register",
+		},
+		Unavailable:    []string{"clone3"},
+		DenyNamespaces: true,
+		SocketFamilies: []string{"AF_UNIX"},
+	}
+}
+
+// LoadSeccompProfile reads the profile named by EXEC_SECCOMP_PROFILE: empty disables
+// filtering, "default" selects DefaultSeccompProfile and anything else is a JSON file.
+func LoadSeccompProfile(source string) (*SeccompProfile, error) {
+	switch source {
+	case "":
+		return nil, nil
+	case "default":
+		return DefaultSeccompProfile(), nil
+	}
+
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seccomp profile: %w", err)
+	}
+	var profile SeccompProfile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("invalid seccomp profile %s: %w", source, err)
+	}
+	if err := profile.validate(); err != nil {
+		return nil, fmt.Errorf("invalid seccomp profile %s: %w", source, err)
+	}
+	return &profile, nil
+}
+
+var (
+	seccompOnce    sync.Once
+	seccompProfile *SeccompProfile
+	seccompErr     error
+)
+
+// configuredSeccompProfile returns the profile from the configuration, loaded once
+func configuredSeccompProfile() (*SeccompProfile, error) {
+	seccompOnce.Do(func() {
+		seccompProfile, seccompErr = LoadSeccompProfile(configuration.GetExecutorConfig().SeccompProfile)
+	})
+	return seccompProfile, seccompErr
+}
+
+// CheckSeccompProfile verifies the configured seccomp profile before grading starts
+func CheckSeccompProfile() error {
+	_, err := configuredSeccompProfile()
+	return err
+}
+
+// killsByDefault reports whether calls the profile does not list are killed
+func (p *SeccompProfile) killsByDefault() (bool, error) {
+	switch strings.ToLower(p.DefaultAction) {
+	case "allow":
+		return false, nil
+	case "kill":
+		return true, nil
+	default:
+		return false, fmt.Errorf("default_action must be allow or kill, got %q", p.DefaultAction)
+	}
+}
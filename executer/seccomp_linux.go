@@ -0,0 +1,148 @@
+package executer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// seccompData offsets of struct seccomp_data; arguments are read by their low 32 bits,
+// which come first on the little-endian architectures the filter is built for
+const (
+	seccompNrOffset   = 0
+	seccompArchOffset = 4
+	seccompArg0Offset = 16
+)
+
+// x32SyscallBit marks system calls of the x32 ABI, which would bypass a filter on numbers
+const x32SyscallBit = 0x40000000
+
+// cloneNamespaceFlags are the flags of clone that create namespaces; they all sit in the
+// low 32 bits of its first argument. CLONE_NEWTIME is left out: only clone3 takes it, and
+// for clone its bit is part of the exit signal.
+const cloneNamespaceFlags = unix.CLONE_NEWNS | unix.CLONE_NEWCGROUP | unix.CLONE_NEWUTS |
+	unix.CLONE_NEWIPC | unix.CLONE_NEWUSER | unix.CLONE_NEWPID | unix.CLONE_NEWNET
+
+var socketFamilies = map[string]uint32{
+	"AF_UNIX":    unix.AF_UNIX,
+	"AF_LOCAL":   unix.AF_LOCAL,
+	"AF_INET":    unix.AF_INET,
+	"AF_INET6":   unix.AF_INET6,
+	"AF_NETLINK": unix.AF_NETLINK,
+	"AF_PACKET":  unix.AF_PACKET,
+}
+
+var errSeccompUnsupported = errors.New("seccomp filters are not supported on this architecture")
+
+func (p *SeccompProfile) validate() error {
+	_, err := p.compile()
+	return err
+}
+
+// compile translates the profile into a BPF program for seccomp. Every rule is a
+// comparison followed by a return, so jumps stay short however long the lists are.
+// Denied calls are checked first, then unavailable ones, the flags of clone, the socket
+// families and allowed calls.
+func (p *SeccompProfile) compile() ([]unix.SockFilter, error) {
+	if seccompArch == 0 {
+		return nil, errSeccompUnsupported
+	}
+	killDefault, err := p.killsByDefault()
+	if err != nil {
+		return nil, err
+	}
+
+	load := func(offset uint32) unix.SockFilter {
+		return unix.SockFilter{Code: unix.BPF_LD | unix.BPF_W | unix.BPF_ABS, K: offset}
+	}
+	ret := func(action uint32) unix.SockFilter {
+		return unix.SockFilter{Code: unix.BPF_RET | unix.BPF_K, K: action}
+	}
+	// jumpUnless skips the next n instructions when A does not equal value
+	jumpUnless := func(value uint32, n uint8) unix.SockFilter {
+		return unix.SockFilter{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, K: value, Jf: n}
+	}
+	kill := ret(unix.SECCOMP_RET_KILL_PROCESS)
+	allow := ret(unix.SECCOMP_RET_ALLOW)
+	enosys := ret(unix.SECCOMP_RET_ERRNO | uint32(unix.ENOSYS))
+
+	filter := []unix.SockFilter{
+		load(seccompArchOffset),
+		{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, K: seccompArch, Jt: 1},
+		kill,
+		load(seccompNrOffset),
+		{Code: unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K, K: x32SyscallBit, Jf: 1},
+		kill,
+	}
+
+	rules := func(names []string, action unix.SockFilter) error {
+		for _, name := range names {
+			nr, ok := syscallNumbers[strings.ToLower(name)]
+			if !ok {
+				return fmt.Errorf("unknown system call %q", name)
+			}
+			filter = append(filter, jumpUnless(uint32(nr), 1), action)
+		}
+		return nil
+	}
+	if err := rules(p.Deny, kill); err != nil {
+		return nil, err
+	}
+	if err := rules(p.Unavailable, enosys); err != nil {
+		return nil, err
+	}
+	if p.DenyNamespaces {
+		// clone(flags, ...): kill when any namespace flag is set, otherwise reload the
+		// call number for the rules that follow
+		filter = append(filter,
+			jumpUnless(uint32(unix.SYS_CLONE), 4),
+			load(seccompArg0Offset),
+			unix.SockFilter{Code: unix.BPF_JMP | unix.BPF_JSET | unix.BPF_K, K: cloneNamespaceFlags, Jf: 1},
+			kill,
+			load(seccompNrOffset),
+		)
+	}
+	if len(p.SocketFamilies) > 0 {
+		// socket(family, ...): the family is the first argument
+		block := []unix.SockFilter{load(seccompArg0Offset)}
+		for _, name := range p.SocketFamilies {
+			family, ok := socketFamilies[strings.ToUpper(name)]
+			if !ok {
+				return nil, fmt.Errorf("unknown socket family %q", name)
+			}
+			block = append(block, jumpUnless(family, 1), allow)
+		}
+		block = append(block, kill)
+		if len(block) > 255 {
+			return nil, fmt.Errorf("too many socket families")
+		}
+		filter = append(filter, jumpUnless(uint32(unix.SYS_SOCKET), uint8(len(block))))
+		filter = append(filter, block...)
+	}
+	if err := rules(p.Allow, allow); err != nil {
+		return nil, err
+	}
+
+	if killDefault {
+		filter = append(filter, kill)
+	} else {
+		filter = append(filter, allow)
+	}
+	return filter, nil
+}
+
+// installSeccomp applies filter to the calling thread, which must be the one that execs
+// the program. no_new_privs is required to install a filter without CAP_SYS_ADMIN.
+func installSeccomp(filter []unix.SockFilter) error {
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to set no_new_privs: %w", err)
+	}
+	program := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	if err := unix.Prctl(unix.PR_SET_SECCOMP, unix.SECCOMP_MODE_FILTER, uintptr(unsafe.Pointer(&program)), 0, 0); err != nil {
+		return fmt.Errorf("failed to install seccomp filter: %w", err)
+	}
+	return nil
+}
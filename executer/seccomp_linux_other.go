@@ -0,0 +1,8 @@
+//go:build linux && !amd64 && !arm64
+
+package executer
+
+// Seccomp filters are only built for amd64 and arm64
+const seccompArch = 0
+
+var syscallNumbers map[string]uintptr
@@ -0,0 +1,94 @@
+package executer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// TestPythonExecutor_Seccomp runs submissions under the default profile and a profile file,
+// checking that forbidden calls are reported as security violations and nothing else is
+func TestPythonExecutor_Seccomp(t *testing.T) {
+	setUp()
+	profileFile := filepath.Join(t.TempDir(), "profile.json")
+	if err := os.WriteFile(profileFile, []byte(`{"default_action": "allow", "deny": ["getpriority"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	custom, err := LoadSeccompProfile(profileFile)
+	if err != nil {
+		t.Fatalf("load profile: %v", err)
+	}
+
+	cases := []struct {
+		name      string
+		profile   *SeccompProfile
+		code      string
+		violation bool
+		stdout    string
+	}{
+		{"hello", DefaultSeccompProfile(), "print('hello')", false, "hello\n"},
+		{"unix socket", DefaultSeccompProfile(), "import socket\nsocket.socket(socket.AF_UNIX).close()\nprint('ok')", false, "ok\n"},
+		{"inet socket", DefaultSeccompProfile(), "import socket\nprint('before', flush=True)\nsocket.socket(socket.AF_INET)\nprint('after')", true, "before\n"},
+		{"ptrace", DefaultSeccompProfile(), "import ctypes\nctypes.CDLL(None).ptrace(0, 0, 0, 0)\nprint('after')", true, ""},
+		{"io_uring", DefaultSeccompProfile(), fmt.Sprintf("import ctypes\nctypes.CDLL(None).syscall(%d, 1, None)\nprint('after')", unix.SYS_IO_URING_SETUP), true, ""},
+		{"clone namespace", DefaultSeccompProfile(), fmt.Sprintf("import ctypes\nctypes.CDLL(None).syscall(%d, 0x%x | 17, 0, 0, 0, 0)\nprint('after')", unix.SYS_CLONE, unix.CLONE_NEWUSER|unix.CLONE_NEWNET), true, ""},
+		{"clone3 unavailable", DefaultSeccompProfile(), fmt.Sprintf("import ctypes\nlibc = ctypes.CDLL(None, use_errno=True)\nprint(libc.syscall(%d, None, 0), ctypes.get_errno())", unix.SYS_CLONE3), false, fmt.Sprintf("-1 %d\n", unix.ENOSYS)},
+		{"threads and processes", DefaultSeccompProfile(), "import subprocess, threading\nthreading.Thread(target=print, args=('thread',)).start()\nprint(subprocess.run(['echo', 'child'], capture_output=True, text=True).stdout, end='')", false, "thread\nchild\n"},
+		{"profile file", custom, "import os\nos.getpriority(os.PRIO_PROCESS, 0)\nprint('after')", true, ""},
+		{"profile file allows", custom, "import socket\nsocket.socket(socket.AF_INET).close()\nprint('ok')", false, "ok\n"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			executor := &PythonExecutor{Limits: pythonExecutor.Limits}
+			executor.Limits.Seccomp = c.profile
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+
+			result, err := executor.Execute(ctx, c.code, "")
+			if err != nil {
+				t.Fatalf("execute: %v", err)
+			}
+			if result.SecurityViolation != c.violation || result.Stdout != c.stdout {
+				t.Fatalf("unexpected result: %+v", result)
+			}
+			if c.violation && result.Err() != ErrSecurityViolation {
+				t.Fatalf("expected a security violation error, got %v", result.Err())
+			}
+			if !c.violation && !result.Succeeded() {
+				t.Fatalf("expected success, got %+v", result)
+			}
+		})
+	}
+}
+
+// TestLoadSeccompProfile rejects profiles naming unknown calls, families or actions
+func TestLoadSeccompProfile(t *testing.T) {
+	if profile, err := LoadSeccompProfile(""); profile != nil || err != nil {
+		t.Fatalf("empty source should disable filtering, got %v, %v", profile, err)
+	}
+	if profile, err := LoadSeccompProfile("default"); err != nil || profile.validate() != nil {
+		t.Fatalf("default profile is invalid: %v", err)
+	}
+
+	invalid := map[string]string{
+		"action":  `{"default_action": "trap"}`,
+		"syscall": `{"default_action": "allow", "deny": ["no_such_call"]}`,
+		"family":  `{"default_action": "allow", "socket_families": ["AF_NOPE"]}`,
+		"json":    `{"default_action": `,
+	}
+	for name, content := range invalid {
+		path := filepath.Join(t.TempDir(), name+".json")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadSeccompProfile(path); err == nil || !strings.Contains(err.Error(), "invalid seccomp profile") {
+			t.Errorf("%s: expected an invalid profile error, got %v", name, err)
+		}
+	}
+}
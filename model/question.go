@@ -0,0 +1,29 @@
+package model
+
+type Question struct {
+	QuestionId  int     `json:"question_id" db:"question_id"`
+	TotalScore  float64 `json:"total_score" db:"total_score"`
+	CompareMode string  `json:"compare_mode" db:"compare_mode"`
+	Language    string  `json:"language" db:"language"`
+	Interpreter string  `json:"interpreter" db:"interpreter"`
+	// TestSuite is an instructor test module run against the submission, see TestFramework
+	TestSuite string `json:"test_suite" db:"test_suite"`
+	// TestFramework runs TestSuite: "unittest" or "pytest"
+	TestFramework string `json:"test_framework" db:"test_framework"`
+	// Checker is a special judge program that decides the testcases instead of CompareMode
+	Checker         string `json:"checker" db:"checker"`
+	CheckerLanguage string `json:"checker_language" db:"checker_language"`
+	// Interactor is a program the submission talks to through stdin and stdout, which decides the testcases
+	Interactor         string `json:"interactor" db:"interactor"`
+	InteractorLanguage string `json:"interactor_language" db:"interactor_language"`
+	// StaticRules holds the JSON rules checked before the submission runs
+	StaticRules string `json:"static_rules" db:"static_rules"`
+	// TimeLimitMs and MemoryLimitMB bound each run of the submission; 0 uses the configured default
+	TimeLimitMs   int `json:"time_limit_ms" db:"time_limit_ms"`
+	MemoryLimitMB int `json:"memory_limit_mb" db:"memory_limit_mb"`
+	// ReferenceSolution is a model answer in Language, timed as the baseline of EfficiencyMultiple
+	ReferenceSolution string `json:"reference_solution" db:"reference_solution"`
+	// EfficiencyMultiple is how many times the reference's CPU time an accepted run may take
+	// for full credit, see service.Efficiency; 0 does not score efficiency
+	EfficiencyMultiple float64 `json:"efficiency_multiple" db:"efficiency_multiple"`
+}
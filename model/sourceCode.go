@@ -11,6 +11,7 @@ type SourceCode struct {
 	Version                 int       `json:"version" db:"version"`
 	Score                   float32   `json:"score" db:"score"`
 	Status                  string    `json:"status" db:"status"`
+	Verdict                 Verdict   `json:"verdict" db:"verdict"`
 	CreatedAt               time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt               time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -0,0 +1,10 @@
+package model
+
+// SourceFile is one further file of a multi-file submission. The main file is kept in
+// SourceCode.SourceCode; these are the modules handed in next to it.
+type SourceFile struct {
+	SourceFileId            int    `json:"source_file_id" db:"source_file_id"`
+	StudentQuestionFileV2Id int    `json:"student_question_file_v2_id" db:"student_question_file_v2_id"`
+	FileName                string `json:"file_name" db:"file_name"`
+	Content                 string `json:"content" db:"content"`
+}
@@ -10,6 +10,15 @@ type Testcase struct {
 	TestcaseOutput string `json:"testcase_output" db:"testcase_output"`
 	Score         float64 `json:"score" db:"score"`
 	RegexMatch    string    `json:"regex_match" db:"regex_match"`
+	CompareMode   string    `json:"compare_mode" db:"compare_mode"`
+	// FunctionName makes this a function testcase, see service.functionInput
+	FunctionName  string    `json:"function_name" db:"function_name"`
+	// TestName makes this a testcase graded by a test of the question's test suite
+	TestName      string    `json:"test_name" db:"test_name"`
+	// TimeLimitMs and MemoryLimitMB override the question's limits for this testcase when set
+	TimeLimitMs   int       `json:"time_limit_ms" db:"time_limit_ms"`
+	MemoryLimitMB int       `json:"memory_limit_mb" db:"memory_limit_mb"`
+	Files         []TestcaseFile `json:"files" db:"-"`
 	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
 }
@@ -23,5 +32,10 @@ type Testcase struct {
 // 		, tc.testcase_output 
 // 		, tc.score
 // 		, tc.regex_match 
+// 		, tc.compare_mode 
+// 		, tc.function_name 
+// 		, tc.test_name 
+// 		, tc.time_limit_ms 
+// 		, tc.memory_limit_mb 
 // 		, tc.created_at 
 // 		, tc.updated_at
\ No newline at end of file
@@ -0,0 +1,40 @@
+package model
+
+// Kinds of testcase files
+const (
+	// TestcaseFileInput is written to the working directory before the submission runs
+	TestcaseFileInput = "input"
+	// TestcaseFileExpected is compared with the file of that name the submission wrote
+	TestcaseFileExpected = "expected"
+)
+
+// TestcaseFile is a file attached to a testcase
+type TestcaseFile struct {
+	TestcaseFileId int    `json:"testcase_file_id" db:"testcase_file_id"`
+	TestcaseId     int    `json:"testcase_id" db:"testcase_id"`
+	FileName       string `json:"file_name" db:"file_name"`
+	Kind           string `json:"kind" db:"kind"`
+	Content        string `json:"content" db:"content"`
+}
+
+// InputFiles returns the input files of the testcase by file name
+func (tc Testcase) InputFiles() map[string]string {
+	files := map[string]string{}
+	for _, f := range tc.Files {
+		if f.Kind == TestcaseFileInput {
+			files[f.FileName] = f.Content
+		}
+	}
+	return files
+}
+
+// ExpectedFiles returns the files the submission must write for the testcase
+func (tc Testcase) ExpectedFiles() []TestcaseFile {
+	var files []TestcaseFile
+	for _, f := range tc.Files {
+		if f.Kind == TestcaseFileExpected {
+			files = append(files, f)
+		}
+	}
+	return files
+}
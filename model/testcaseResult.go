@@ -11,7 +11,15 @@ type TestcaseResult struct {
 	TestcaseId             int       `json:"testcase_id" db:"testcase_id"`
 	Score                  int       `json:"score" db:"score"`
 	Status                 string    `json:"status" db:"status"`
+	Verdict                Verdict   `json:"verdict" db:"verdict"`
 	TestOutputText        string    `json:"test_output_text" db:"test_output_text"`
+	// TimeLimitMs and MemoryLimitMB are the limits the testcase was graded with
+	TimeLimitMs            int       `json:"time_limit_ms" db:"time_limit_ms"`
+	MemoryLimitMB          int       `json:"memory_limit_mb" db:"memory_limit_mb"`
+	// WallTimeMs, CPUTimeMs (user + system) and PeakMemoryKB (peak RSS) are what the run took
+	WallTimeMs             int       `json:"wall_time_ms" db:"wall_time_ms"`
+	CPUTimeMs              int       `json:"cpu_time_ms" db:"cpu_time_ms"`
+	PeakMemoryKB           int       `json:"peak_memory_kb" db:"peak_memory_kb"`
 	CheckedUserId          int       `json:"checked_user_id" db:"checked_user_id"`
 	CheckedAt              time.Time `json:"checked_at" db:"checked_at"`
 	CreatedAt              time.Time `json:"created_at" db:"created_at"`
@@ -0,0 +1,103 @@
+package model
+
+// Verdict classifies the outcome of a testcase run, and in aggregate of a whole submission.
+// It is stored in the verdict columns next to the P/F/N status.
+type Verdict string
+
+const (
+	VerdictAccepted      Verdict = "AC"
+	VerdictWrongAnswer   Verdict = "WA"
+	VerdictPartial       Verdict = "PA"
+	VerdictRuntimeError  Verdict = "RE"
+	VerdictCompileError  Verdict = "CE"
+	VerdictTimeLimit     Verdict = "TLE"
+	VerdictMemoryLimit   Verdict = "MLE"
+	VerdictOutputLimit   Verdict = "OLE"
+	VerdictInternalError Verdict = "IE"
+	// VerdictSecurityViolation marks a program killed for a forbidden system call
+	VerdictSecurityViolation Verdict = "SV"
+	// VerdictRuleViolation marks a submission that broke a hard static rule of its question
+	// and was not run
+	VerdictRuleViolation Verdict = "RV"
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case VerdictAccepted:
+		return "Accepted"
+	case VerdictWrongAnswer:
+		return "Wrong Answer"
+	case VerdictPartial:
+		return "Partial"
+	case VerdictRuntimeError:
+		return "Runtime Error"
+	case VerdictCompileError:
+		return "Compile Error"
+	case VerdictTimeLimit:
+		return "Time Limit Exceeded"
+	case VerdictMemoryLimit:
+		return "Memory Limit Exceeded"
+	case VerdictOutputLimit:
+		return "Output Limit Exceeded"
+	case VerdictInternalError:
+		return "Internal Error"
+	case VerdictSecurityViolation:
+		return "Security Violation"
+	case VerdictRuleViolation:
+		return "Rule Violation"
+	default:
+		return string(v)
+	}
+}
+
+// Status maps the verdict to the legacy P/F/N status of a testcase result. An internal
+// error is the grader's failure, not the submission's, so it is N and left out of the score.
+func (v Verdict) Status() string {
+	switch v {
+	case VerdictAccepted:
+		return "P"
+	case VerdictInternalError:
+		return "N"
+	default:
+		return "F"
+	}
+}
+
+// AggregateVerdict summarizes the testcase verdicts of a submission. All accepted gives
+// Accepted; an internal error, security violation or compile error anywhere wins, in that
+// order; otherwise a mix with any accepted or partial testcase is Partial, and a
+// submission that passed nothing takes the verdict of its first testcase.
+func AggregateVerdict(verdicts []Verdict) Verdict {
+	if len(verdicts) == 0 {
+		return ""
+	}
+
+	accepted, partial, violation, compileError := 0, false, false, false
+	for _, v := range verdicts {
+		switch v {
+		case VerdictInternalError:
+			return v
+		case VerdictSecurityViolation:
+			violation = true
+		case VerdictCompileError:
+			compileError = true
+		case VerdictAccepted:
+			accepted++
+		case VerdictPartial:
+			partial = true
+		}
+	}
+
+	switch {
+	case violation:
+		return VerdictSecurityViolation
+	case compileError:
+		return VerdictCompileError
+	case accepted == len(verdicts):
+		return VerdictAccepted
+	case accepted > 0 || partial:
+		return VerdictPartial
+	default:
+		return verdicts[0]
+	}
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+// Files a checker finds in its working directory
+const (
+	checkerInputFile    = "input.txt"
+	checkerExpectedFile = "expected.txt"
+	checkerOutputFile   = "output.txt"
+)
+
+// Checker is a special judge: a program of the question that decides whether an output is
+// correct when several are. It reads the testcase input, the expected output and the
+// student's output from input.txt, expected.txt and output.txt, and prints its decision:
+//
+//	AC | WA | PA <fraction>
+//	<message, any number of lines>
+//
+// It runs on the interpreter the question's submissions run on, under the same limits as
+// the submission for the testcase it checks.
+type Checker struct {
+	// runners run the checker under each of the limits of the question's testcases
+	runners map[testLimits]executer.Executor
+	code    string
+}
+
+// newChecker returns the checker of the question, or nil when it has none. It can check
+// testcases with any of limits, applied to defaults.
+func newChecker(question model.Question, opts Options, defaults executer.Limits, limits []testLimits) (*Checker, error) {
+	if question.Checker == "" {
+		return nil, nil
+	}
+	program := model.Question{Language: question.CheckerLanguage, Interpreter: question.Interpreter}
+	runners, err := newRunners(program, opts, defaults, limits)
+	if err != nil {
+		return nil, fmt.Errorf("checker: %v", err)
+	}
+	return &Checker{runners: runners, code: question.Checker}, nil
+}
+
+// Check runs the checker on one output of a testcase with limits. The error is set when
+// the checker itself failed, which is the grader's problem rather than the student's.
+func (c *Checker) Check(ctx context.Context, limits testLimits, input string, expected string, output string) (Comparison, error) {
+	runner, ok := c.runners[limits]
+	if !ok {
+		return Comparison{}, fmt.Errorf("checker has no executor for the limits %+v", limits)
+	}
+	result, err := runner.Run(ctx, executer.Input{
+		Code: c.code,
+		Files: map[string]string{
+			checkerInputFile:    input,
+			checkerExpectedFile: expected,
+			checkerOutputFile:   output,
+		},
+	})
+	if err != nil {
+		return Comparison{}, fmt.Errorf("checker could not be run: %v", err)
+	}
+	if !result.Succeeded() {
+		message := fmt.Sprintf("checker failed (exit code %d)", result.ExitCode)
+		if err := result.Err(); err != nil {
+			message = "checker failed: " + err.Error()
+		}
+		if result.Stderr != "" {
+			message += "\n" + result.Stderr
+		}
+		return Comparison{}, fmt.Errorf("%s", message)
+	}
+	comparison, err := parseCheckerOutput(result.Stdout)
+	if err != nil {
+		return Comparison{}, fmt.Errorf("checker: %v", err)
+	}
+	return comparison, nil
+}
+
+// parseCheckerOutput reads the decision of a checker or interactor
+func parseCheckerOutput(stdout string) (Comparison, error) {
+	first, message, _ := strings.Cut(strings.TrimLeft(stdout, "\r\n"), "\n")
+	message = strings.TrimSpace(message)
+	fields := strings.Fields(first)
+	if len(fields) == 0 {
+		return Comparison{}, fmt.Errorf("no verdict was given")
+	}
+
+	switch model.Verdict(strings.ToUpper(fields[0])) {
+	case model.VerdictAccepted:
+		return Comparison{Match: true, Similarity: 1, Reason: message}, nil
+	case model.VerdictWrongAnswer:
+		return Comparison{Reason: checkerReason(message, "rejected by the judge")}, nil
+	case model.VerdictPartial:
+		if len(fields) < 2 {
+			return Comparison{}, fmt.Errorf("verdict PA needs a score fraction")
+		}
+		fraction, err := strconv.ParseFloat(fields[1], 32)
+		if err != nil || fraction < 0 || fraction > 1 {
+			return Comparison{}, fmt.Errorf("score fraction must be between 0 and 1, got %q", fields[1])
+		}
+		return Comparison{Similarity: float32(fraction), Reason: checkerReason(message, "partially accepted by the judge")}, nil
+	default:
+		return Comparison{}, fmt.Errorf("unknown verdict %q", fields[0])
+	}
+}
+
+func checkerReason(message string, fallback string) string {
+	if message == "" {
+		return fallback
+	}
+	return message
+}
+
+// checkerComparator adapts a checker to a Comparator for one testcase, keeping the error of
+// a failed checker run for the caller
+type checkerComparator struct {
+	ctx     context.Context
+	checker *Checker
+	limits  testLimits
+	input   string
+	err     error
+}
+
+func (c *checkerComparator) Compare(got string, want string) Comparison {
+	comparison, err := c.checker.Check(c.ctx, c.limits, c.input, want, got)
+	if err != nil && c.err == nil {
+		c.err = err
+	}
+	return comparison
+}
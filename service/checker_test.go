@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+// permutationChecker accepts any order of the expected numbers and gives partial credit
+// for the fraction of them the output contains
+const permutationChecker = `
+expected = open("expected.txt").read().split()
+output = open("output.txt").read().split()
+n = int(open("input.txt").read())
+if len(expected) != n:
+    raise SystemExit("bad testcase")
+if sorted(output) == sorted(expected):
+    print("AC")
+elif not set(output) & set(expected):
+    print("WA")
+    print("no expected number in the output")
+else:
+    print("PA", len(set(output) & set(expected)) / n)
+    print("some numbers are missing")
+`
+
+// TestChecker runs a permutation checker and checkers that fail
+func TestChecker(t *testing.T) {
+	cases := []struct {
+		name       string
+		code       string
+		input      string
+		output     string
+		match      bool
+		similarity float32
+		reason     string
+		err        string
+	}{
+		{"permutation", permutationChecker, "3", "3 1 2", true, 1, "", ""},
+		{"partial", permutationChecker, "3", "1 2", false, 2.0 / 3, "some numbers are missing", ""},
+		{"wrong", permutationChecker, "3", "7", false, 0, "no expected number in the output", ""},
+		{"crash", permutationChecker, "4", "1 2 3", false, 0, "", "bad testcase"},
+		{"no verdict", "print()", "3", "1 2 3", false, 0, "", "no verdict"},
+		{"unknown verdict", "print('OK')", "3", "1 2 3", false, 0, "", "unknown verdict"},
+		{"fraction", "print('PA 2')", "3", "1 2 3", false, 0, "", "between 0 and 1"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			checker := &Checker{runners: map[testLimits]executer.Executor{{}: &executer.PythonExecutor{}}, code: c.code}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			comparison, err := checker.Check(ctx, testLimits{}, c.input, "1 2 3", c.output)
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("expected an error containing %q, got %v", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("check: %v", err)
+			}
+			if comparison.Match != c.match || comparison.Similarity-c.similarity > 1e-6 || c.similarity-comparison.Similarity > 1e-6 || comparison.Reason != c.reason {
+				t.Fatalf("unexpected comparison %+v", comparison)
+			}
+		})
+	}
+}
+
+// TestNewChecker checks that the checker runs on the interpreter configured for the question,
+// under the limits of the testcase it checks
+func TestNewChecker(t *testing.T) {
+	question := model.Question{Checker: permutationChecker}
+	limits := testLimits{Time: 2 * time.Second, MemoryMB: 64}
+	checker, err := newChecker(question, Options{Interpreter: "python3"}, executer.Limits{}, []testLimits{limits})
+	if err != nil {
+		t.Fatalf("newChecker: %v", err)
+	}
+	python, ok := checker.runners[limits].(*executer.PythonExecutor)
+	if !ok || python.Interpreter != "python3" {
+		t.Fatalf("checker does not run on the configured interpreter: %+v", checker.runners)
+	}
+	if python.Limits != limits.apply(executer.Limits{}) {
+		t.Fatalf("checker does not run under the limits of the testcase: %+v", python.Limits)
+	}
+	if _, err := newChecker(question, Options{Interpreter: "python0"}, executer.Limits{}, []testLimits{limits}); err == nil {
+		t.Fatalf("newChecker should fail for an interpreter that is not configured")
+	}
+}
@@ -0,0 +1,315 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"python-runner/model"
+)
+
+// DefaultComparator is the comparator used when neither the testcase nor the question selects one.
+const DefaultComparator = "normalized"
+
+// Comparison is the outcome of comparing a program output with the expected output
+type Comparison struct {
+	Match      bool
+	Similarity float32
+	// Reason explains a failed comparison and is recorded with the test result
+	Reason string
+}
+
+// Comparator judges a program output against the expected output of a testcase
+type Comparator interface {
+	Compare(got string, want string) Comparison
+}
+
+// ComparatorFunc adapts an ordinary function to the Comparator interface
+type ComparatorFunc func(got string, want string) Comparison
+
+func (f ComparatorFunc) Compare(got string, want string) Comparison {
+	return f(got, want)
+}
+
+// ComparatorFactory builds a comparator from the options given in its spec
+type ComparatorFactory func(options map[string]string) (Comparator, error)
+
+var (
+	comparatorsMu sync.RWMutex
+	comparators   = map[string]ComparatorFactory{}
+)
+
+func init() {
+	RegisterComparator("normalized", staticComparator(ComparatorFunc(compareNormalized)))
+	RegisterComparator("exact", staticComparator(ComparatorFunc(compareExact)))
+	RegisterComparator("whitespace", staticComparator(ComparatorFunc(compareWhitespace)))
+	RegisterComparator("case-sensitive", staticComparator(ComparatorFunc(compareCaseSensitive)))
+	RegisterComparator("tokens", staticComparator(ComparatorFunc(compareTokens)))
+	RegisterComparator("unordered", staticComparator(ComparatorFunc(compareUnordered)))
+	RegisterComparator("numeric", newNumericComparator)
+	RegisterComparator("regex", newRegexComparator)
+}
+
+// RegisterComparator makes a comparator available under name, replacing any previous registration
+func RegisterComparator(name string, factory ComparatorFactory) {
+	comparatorsMu.Lock()
+	defer comparatorsMu.Unlock()
+	comparators[name] = factory
+}
+
+// ComparatorNames returns the registered comparator names in sorted order
+func ComparatorNames() []string {
+	comparatorsMu.RLock()
+	defer comparatorsMu.RUnlock()
+	names := make([]string, 0, len(comparators))
+	for name := range comparators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewComparator builds a comparator from a spec of the form "name" or
+// "name:key=value,key=value", as stored in the compare_mode columns.
+func NewComparator(spec string) (Comparator, error) {
+	name, rawOptions, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	if name == "" {
+		name = DefaultComparator
+	}
+
+	options := map[string]string{}
+	for _, opt := range strings.Split(rawOptions, ",") {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(opt, "=")
+		options[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	comparatorsMu.RLock()
+	factory, ok := comparators[name]
+	comparatorsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown comparator %q", name)
+	}
+	return factory(options)
+}
+
+// selectComparator picks the comparator for a testcase and the text it should compare against.
+// The testcase compare_mode comes first, then a RegexMatch of the testcase, which is judged
+// by its pattern, then the question's compare_mode and finally DefaultComparator. A spec
+// that does not parse is an error rather than a fallback, so a typo never grades with
+// other rules than the instructor meant.
+func selectComparator(question model.Question, tc model.Testcase) (Comparator, string, error) {
+	spec := tc.CompareMode
+	if spec == "" && tc.RegexMatch != "" {
+		spec = "regex"
+	}
+	if spec == "" {
+		spec = question.CompareMode
+	}
+
+	comparator, err := NewComparator(spec)
+	if err != nil {
+		return nil, "", fmt.Errorf("compare mode %q of testcase %d: %v", spec, tc.TestcaseId, err)
+	}
+
+	if name, _, _ := strings.Cut(spec, ":"); name == "regex" {
+		return comparator, tc.RegexMatch, nil
+	}
+	return comparator, tc.TestcaseOutput, nil
+}
+
+func staticComparator(c Comparator) ComparatorFactory {
+	return func(options map[string]string) (Comparator, error) {
+		if len(options) > 0 {
+			return nil, fmt.Errorf("comparator does not take options")
+		}
+		return c, nil
+	}
+}
+
+// newRegexComparator treats the expected text as a RegexMatch pattern.
+// The "anchor" option selects "full" (default) or "line" matching.
+func newRegexComparator(options map[string]string) (Comparator, error) {
+	prefix := ""
+	switch options["anchor"] {
+	case "", "full":
+	case "line":
+		prefix = regexLinePrefix
+	default:
+		return nil, fmt.Errorf("regex anchor must be full or line, got %q", options["anchor"])
+	}
+	return ComparatorFunc(func(got string, want string) Comparison {
+		if prefix != "" && !strings.HasPrefix(want, regexLinePrefix) && !strings.HasPrefix(want, regexFullPrefix) {
+			want = prefix + want
+		}
+		match, similarity, reason := compareRegex(got, want)
+		return Comparison{Match: match, Similarity: similarity, Reason: reason}
+	}), nil
+}
+
+func compareNormalized(got string, want string) Comparison {
+	match, similarity := compareResult(strings.TrimSpace(got), strings.TrimSpace(want))
+	comparison := Comparison{Match: match, Similarity: similarity}
+	if !match {
+		comparison.Reason = compareLines(splitLines(got), splitLines(want), normalizeLine).Reason
+	}
+	return comparison
+}
+
+func compareResult(got string, want string) (bool, float32) {
+
+	// replace all \r\n with \n
+	var similarity float32 = 0
+	got = strings.ReplaceAll(got, "\r\n", "\n")
+	want = strings.ReplaceAll(want, "\r\n", "\n")
+
+	gotLines := strings.Split(strings.TrimSpace(got), "\n")
+	wantLines := strings.Split(strings.TrimSpace(want), "\n")
+
+	min_len := len(gotLines)
+	if len(wantLines) < min_len {
+		min_len = len(wantLines)
+	}
+
+	for i := 0; i < min_len; i++ {
+		gotLine := normalizeLine(gotLines[i])
+		wantLine := normalizeLine(wantLines[i])
+		// compare
+		if gotLine == wantLine {
+			similarity += 1.0
+		}
+	}
+	return similarity/float32(len(wantLines)) == 1.0, similarity / float32(len(wantLines))
+}
+
+func normalizeLine(s string) string {
+	s = strings.ReplaceAll(s, ":", "")
+	s = strings.ReplaceAll(s, " ", "")
+	s = strings.TrimSpace(s)
+	s = strings.ToLower(s)
+	return s
+}
+
+// compareExact requires identical lines; only line endings and the final newline are ignored
+func compareExact(got string, want string) Comparison {
+	trim := func(s string) string {
+		return strings.TrimRight(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	}
+	return compareLines(strings.Split(trim(got), "\n"), strings.Split(trim(want), "\n"), nil)
+}
+
+// compareWhitespace ignores leading, trailing and repeated whitespace within each line
+func compareWhitespace(got string, want string) Comparison {
+	return compareLines(splitLines(got), splitLines(want), func(s string) string {
+		return strings.Join(strings.Fields(s), " ")
+	})
+}
+
+// compareCaseSensitive applies the legacy normalization but keeps letter case
+func compareCaseSensitive(got string, want string) Comparison {
+	return compareLines(splitLines(got), splitLines(want), func(s string) string {
+		s = strings.ReplaceAll(s, ":", "")
+		s = strings.ReplaceAll(s, " ", "")
+		return strings.TrimSpace(s)
+	})
+}
+
+// compareTokens compares the whitespace-separated tokens of both outputs, ignoring line layout
+func compareTokens(got string, want string) Comparison {
+	gotTokens := strings.Fields(got)
+	wantTokens := strings.Fields(want)
+	return compareSequence("token", gotTokens, wantTokens, func(i int) bool {
+		return gotTokens[i] == wantTokens[i]
+	})
+}
+
+// compareUnordered accepts the expected lines in any order, using the legacy line normalization
+func compareUnordered(got string, want string) Comparison {
+	gotLines := splitLines(got)
+	wantLines := splitLines(want)
+	if len(wantLines) == 0 {
+		if len(gotLines) == 0 {
+			return Comparison{Match: true, Similarity: 1}
+		}
+		return Comparison{Reason: fmt.Sprintf("expected no output, got %d lines", len(gotLines))}
+	}
+
+	remaining := map[string]int{}
+	for _, line := range gotLines {
+		remaining[normalizeLine(line)]++
+	}
+
+	var matched float32 = 0
+	reason := ""
+	for _, line := range wantLines {
+		key := normalizeLine(line)
+		if remaining[key] > 0 {
+			remaining[key]--
+			matched += 1.0
+		} else if reason == "" {
+			reason = fmt.Sprintf("expected line %q not found in output", line)
+		}
+	}
+	if reason == "" && len(gotLines) != len(wantLines) {
+		reason = fmt.Sprintf("output has %d lines, expected %d", len(gotLines), len(wantLines))
+	}
+
+	similarity := matched / float32(len(wantLines))
+	return Comparison{Match: reason == "", Similarity: similarity, Reason: reason}
+}
+
+// compareLines compares lines position by position after applying normalize (if any)
+func compareLines(gotLines []string, wantLines []string, normalize func(string) string) Comparison {
+	if normalize == nil {
+		normalize = func(s string) string { return s }
+	}
+	return compareSequence("line", gotLines, wantLines, func(i int) bool {
+		return normalize(gotLines[i]) == normalize(wantLines[i])
+	})
+}
+
+// compareSequence scores a positional comparison of two sequences. The similarity is the
+// fraction of expected items that matched and a match also requires equal lengths.
+func compareSequence(unit string, got []string, want []string, equal func(i int) bool) Comparison {
+	if len(want) == 0 {
+		if len(got) == 0 {
+			return Comparison{Match: true, Similarity: 1}
+		}
+		return Comparison{Reason: fmt.Sprintf("expected no output, got %d %ss", len(got), unit)}
+	}
+
+	var matched float32 = 0
+	reason := ""
+	for i := range want {
+		if i >= len(got) {
+			if reason == "" {
+				reason = fmt.Sprintf("%s %d missing, expected %q", unit, i+1, want[i])
+			}
+			continue
+		}
+		if equal(i) {
+			matched += 1.0
+		} else if reason == "" {
+			reason = fmt.Sprintf("%s %d: got %q, expected %q", unit, i+1, got[i], want[i])
+		}
+	}
+	if reason == "" && len(got) > len(want) {
+		reason = fmt.Sprintf("output has %d extra %ss", len(got)-len(want), unit)
+	}
+
+	similarity := matched / float32(len(want))
+	return Comparison{Match: reason == "", Similarity: similarity, Reason: reason}
+}
+
+// splitLines normalizes line endings and splits the trimmed text into lines
+func splitLines(s string) []string {
+	s = strings.TrimSpace(strings.ReplaceAll(s, "\r\n", "\n"))
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"testing"
+
+	"python-runner/model"
+)
+
+// TestComparators runs each built-in comparator through matching and mismatching outputs
+func TestComparators(t *testing.T) {
+	cases := []struct {
+		spec      string
+		got       string
+		want      string
+		wantMatch bool
+		wantSim   float32
+	}{
+		{"normalized", "Answer: 42\n", "answer 42", true, 1},
+		{"normalized", "1\n3", "1\n2", false, 0.5},
+		{"exact", "a b\r\n", "a b", true, 1},
+		{"exact", "a  b", "a b", false, 0},
+		{"whitespace", "  a   b \n c", "a b\nc", true, 1},
+		{"whitespace", "A b", "a b", false, 0},
+		{"case-sensitive", "Answer: 42", "Answer 42", true, 1},
+		{"case-sensitive", "answer: 42", "Answer 42", false, 0},
+		{"tokens", "1 2\n3", "1\n2 3", true, 1},
+		{"tokens", "1 2 3 4", "1 2 3", false, 1},
+		{"unordered", "b\na\nc", "a\nb\nc", true, 1},
+		{"unordered", "a\na", "a\nb", false, 0.5},
+		{"unordered", "", "", true, 1},
+		{"unordered", "a\nb", "", false, 0},
+		{"numeric", "x 0.30000000000000004", "x 0.3", true, 1},
+		{"numeric", "x 0.31", "x 0.3", false, 0},
+		{"regex", "id=17", `id=\d+`, true, 1},
+		{"regex:anchor=line", "a1\nb2", "a\\d\nc\\d", false, 0.5},
+	}
+
+	for _, c := range cases {
+		comparator, err := NewComparator(c.spec)
+		if err != nil {
+			t.Fatalf("NewComparator(%q): %v", c.spec, err)
+		}
+		result := comparator.Compare(c.got, c.want)
+		if result.Match != c.wantMatch || result.Similarity != c.wantSim {
+			t.Errorf("%s: Compare(%q, %q) = %v, %v; want %v, %v", c.spec, c.got, c.want, result.Match, result.Similarity, c.wantMatch, c.wantSim)
+		}
+		if !result.Match && result.Reason == "" {
+			t.Errorf("%s: Compare(%q, %q) failed without a reason", c.spec, c.got, c.want)
+		}
+	}
+}
+
+// TestNewComparator_InvalidSpecs verifies unknown names and bad options are rejected
+func TestNewComparator_InvalidSpecs(t *testing.T) {
+	for _, spec := range []string{"nope", "exact:x=1", "regex:anchor=middle"} {
+		if _, err := NewComparator(spec); err == nil {
+			t.Errorf("NewComparator(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+// TestSelectComparator checks testcase, RegexMatch and question precedence
+func TestSelectComparator(t *testing.T) {
+	question := model.Question{CompareMode: "exact"}
+
+	comparator, want, err := selectComparator(question, model.Testcase{TestcaseOutput: "Hello"})
+	if err != nil || want != "Hello" || comparator.Compare("hello", want).Match {
+		t.Fatalf("question compare_mode should select the exact comparator (%v)", err)
+	}
+
+	tc := model.Testcase{TestcaseOutput: "Hello", RegexMatch: "H.*"}
+	comparator, want, err = selectComparator(question, tc)
+	if err != nil || want != "H.*" || !comparator.Compare("Hi", want).Match {
+		t.Fatalf("RegexMatch of the testcase should win over the question compare_mode (%v)", err)
+	}
+
+	tc.CompareMode = "whitespace"
+	comparator, want, err = selectComparator(question, tc)
+	if err != nil || want != "Hello" || !comparator.Compare(" Hello ", want).Match {
+		t.Fatalf("testcase compare_mode should win over RegexMatch (%v)", err)
+	}
+
+	comparator, want, err = selectComparator(model.Question{}, model.Testcase{TestcaseOutput: "Hello"})
+	if err != nil || want != "Hello" || !comparator.Compare("hello", want).Match {
+		t.Fatalf("the default comparator should be used without any compare_mode (%v)", err)
+	}
+
+	for _, c := range []struct {
+		question model.Question
+		tc       model.Testcase
+	}{
+		{model.Question{CompareMode: "bogus"}, model.Testcase{}},
+		{model.Question{}, model.Testcase{CompareMode: "numeric:tol=x"}},
+	} {
+		if _, _, err := selectComparator(c.question, c.tc); err == nil {
+			t.Errorf("an invalid compare mode of %+v / %+v should be an error", c.question, c.tc)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+// efficiencyFloor is the least CPU time a baseline counts as, so runs too short to time
+// reliably are not told apart
+const efficiencyFloor = 50 * time.Millisecond
+
+// Efficiency scores how fast a submission is against the question's reference solution. An
+// accepted run keeps full credit when its CPU time is within multiple times the time the
+// reference takes on the same testcase; beyond that the credit falls in proportion.
+// The reference is timed once for each testcase, see referenceTimes.
+type Efficiency struct {
+	multiple  float64
+	reference Submission
+}
+
+// newEfficiency returns the efficiency rule of the question, or nil when it has none. The
+// multiple from the options replaces the question's.
+func newEfficiency(question model.Question, opts Options) (*Efficiency, error) {
+	multiple := question.EfficiencyMultiple
+	if opts.EfficiencyMultiple > 0 {
+		multiple = opts.EfficiencyMultiple
+	}
+	if multiple <= 0 {
+		return nil, nil
+	}
+	if question.ReferenceSolution == "" {
+		return nil, fmt.Errorf("efficiency scoring needs a reference solution")
+	}
+	if multiple < 1 {
+		return nil, fmt.Errorf("efficiency multiple %g is below 1, so the reference solution itself would lose credit", multiple)
+	}
+	return &Efficiency{multiple: multiple, reference: Submission{Code: question.ReferenceSolution}}, nil
+}
+
+// referenceTimes holds the CPU time of each reference run by referenceKey, so a reference
+// solution runs once for a testcase in this process rather than after every accepted run
+var referenceTimes sync.Map
+
+// referenceKey identifies a run of a reference solution by its input and the program that
+// runs it
+func referenceKey(runner executer.Executor, input executer.Input) ([sha256.Size]byte, error) {
+	program := runner.Name()
+	if python, ok := runner.(*executer.PythonExecutor); ok {
+		program += " " + python.Interpreter
+	}
+	encoded, err := json.Marshal(struct {
+		Program string
+		Input   executer.Input
+	}{program, input})
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(encoded), nil
+}
+
+// judge compares run with the CPU time the reference solution takes for tc on runner,
+// timing it the first time, and returns the fraction of the score run keeps with a note
+// on how it compared. A reference that fails to run gives no baseline and leaves the
+// score whole.
+func (e *Efficiency) judge(ctx context.Context, runner executer.Executor, tc model.Testcase, run executer.ExecutionResult) (float64, string) {
+	input, err := testcaseInput(e.reference, tc)
+	if err != nil {
+		return 1, fmt.Sprintf("efficiency not scored: %v", err)
+	}
+	key, err := referenceKey(runner, input)
+	if err != nil {
+		return 1, fmt.Sprintf("efficiency not scored: %v", err)
+	}
+	cached, ok := referenceTimes.Load(key)
+	if !ok {
+		reference, err := runner.Run(ctx, input)
+		if err == nil && !reference.Succeeded() {
+			err = reference.Err()
+		}
+		if err != nil {
+			return 1, fmt.Sprintf("efficiency not scored: the reference solution failed: %v", err)
+		}
+		cached, _ = referenceTimes.LoadOrStore(key, reference.CPUTime)
+	}
+	took := cached.(time.Duration)
+
+	factor := efficiencyFactor(run.CPUTime, took, e.multiple)
+	baseline := max(took, efficiencyFloor)
+	if factor == 1 {
+		return 1, ""
+	}
+	return factor, fmt.Sprintf("efficiency: %v of CPU time is %.1fx the reference solution's %v, above the allowed %gx, keeping %.0f%% of the score",
+		run.CPUTime.Round(time.Millisecond), float64(run.CPUTime)/float64(baseline), baseline.Round(time.Millisecond), e.multiple, factor*100)
+}
+
+// efficiencyFactor is the fraction of the score kept by a run that took took against a
+// reference that took baseline: all of it up to multiple times the baseline, then the
+// allowed time over the time taken
+func efficiencyFactor(took time.Duration, baseline time.Duration, multiple float64) float64 {
+	allowed := time.Duration(float64(max(baseline, efficiencyFloor)) * multiple)
+	if took <= allowed {
+		return 1
+	}
+	return float64(allowed) / float64(took)
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+func TestEfficiencyFactor(t *testing.T) {
+	cases := []struct {
+		took, baseline time.Duration
+		multiple       float64
+		want           float64
+	}{
+		{100 * time.Millisecond, 100 * time.Millisecond, 2, 1},
+		{200 * time.Millisecond, 100 * time.Millisecond, 2, 1},
+		{400 * time.Millisecond, 100 * time.Millisecond, 2, 0.5},
+		// Baselines below the floor count as the floor
+		{90 * time.Millisecond, time.Millisecond, 2, 1},
+		{200 * time.Millisecond, 0, 2, 0.5},
+	}
+	for _, c := range cases {
+		if got := efficiencyFactor(c.took, c.baseline, c.multiple); got != c.want {
+			t.Errorf("efficiencyFactor(%v, %v, %g) = %g, want %g", c.took, c.baseline, c.multiple, got, c.want)
+		}
+	}
+}
+
+// countingExecutor counts the runs of the executor it wraps
+type countingExecutor struct {
+	executer.Executor
+	runs int
+}
+
+func (c *countingExecutor) Run(ctx context.Context, input executer.Input) (executer.ExecutionResult, error) {
+	c.runs++
+	return c.Executor.Run(ctx, input)
+}
+
+// TestEfficiency checks that an accepted run slower than the reference allows loses credit,
+// that the reference is timed once for a testcase and that a reference which fails leaves
+// the score whole
+func TestEfficiency(t *testing.T) {
+	if e, err := newEfficiency(model.Question{}, Options{}); e != nil || err != nil {
+		t.Fatalf("a question without a multiple should not score efficiency: %v %v", e, err)
+	}
+	if _, err := newEfficiency(model.Question{EfficiencyMultiple: 2}, Options{}); err == nil {
+		t.Fatalf("a multiple without a reference solution should be rejected")
+	}
+	if _, err := newEfficiency(model.Question{ReferenceSolution: "pass", EfficiencyMultiple: 2}, Options{EfficiencyMultiple: 0.5}); err == nil {
+		t.Fatalf("a multiple below 1 should be rejected")
+	}
+
+	ctx := context.Background()
+	runner := &executer.PythonExecutor{}
+	tc := model.Testcase{TestcaseId: 1, TestcaseInput: "3\n", TestcaseOutput: "6"}
+	e, err := newEfficiency(model.Question{ReferenceSolution: "print(int(input()) * 2)", EfficiencyMultiple: 2}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if factor, note := e.judge(ctx, runner, tc, executer.ExecutionResult{CPUTime: 10 * time.Millisecond}); factor != 1 || note != "" {
+		t.Errorf("a fast run lost credit: %g %q", factor, note)
+	}
+	factor, note := e.judge(ctx, runner, tc, executer.ExecutionResult{CPUTime: 10 * time.Second})
+	if factor <= 0 || factor >= 0.1 || !strings.HasPrefix(note, "efficiency:") {
+		t.Errorf("a slow run kept too much credit: %g %q", factor, note)
+	}
+
+	// The reference is timed once for a testcase, not after every accepted run
+	counting := &countingExecutor{Executor: runner}
+	timed := model.Testcase{TestcaseId: 2, TestcaseInput: "4\n", TestcaseOutput: "8"}
+	for i := 0; i < 3; i++ {
+		e.judge(ctx, counting, timed, executer.ExecutionResult{CPUTime: 10 * time.Millisecond})
+	}
+	if counting.runs != 1 {
+		t.Errorf("the reference solution ran %d times for one testcase", counting.runs)
+	}
+
+	broken, _ := newEfficiency(model.Question{ReferenceSolution: "raise ValueError", EfficiencyMultiple: 2}, Options{})
+	if factor, note := broken.judge(ctx, runner, tc, executer.ExecutionResult{CPUTime: 10 * time.Second}); factor != 1 || !strings.Contains(note, "reference solution failed") {
+		t.Errorf("a failing reference should leave the score whole: %g %q", factor, note)
+	}
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+// functionHarness is the main file of function testcases, see function_harness.py
+//
+//go:embed function_harness.py
+var functionHarness string
+
+// Files the harness shares with the grader in the working directory
+const (
+	functionModule     = "submission"
+	functionCallFile   = "_harness_call.json"
+	functionResultFile = "_harness_result.json"
+)
+
+// sourceDir holds the files of the submission in the runs of a harness or the static
+// analyzer. Unlike the working directory it is not on the import path when the run
+// starts, so a module of the submission named like one they import, such as json.py,
+// cannot take its place.
+const sourceDir = "_submission"
+
+// harnessModules places the submission, as functionModule, and its modules in sourceDir.
+// The submission may not contain a module named functionModule or one of reserved.
+func harnessModules(submission Submission, reserved ...string) (map[string]string, error) {
+	modules := map[string]string{path.Join(sourceDir, functionModule+".py"): submission.Code}
+	for name, content := range submission.Modules {
+		for _, taken := range append(reserved, functionModule+".py") {
+			if name == taken {
+				return nil, fmt.Errorf("the submission may not contain a module named %s", name)
+			}
+		}
+		modules[path.Join(sourceDir, name)] = content
+	}
+	return modules, nil
+}
+
+// functionCall is what the harness reads from functionCallFile
+type functionCall struct {
+	Module   string                     `json:"module"`
+	Function string                     `json:"function"`
+	Args     []json.RawMessage          `json:"args"`
+	Kwargs   map[string]json.RawMessage `json:"kwargs"`
+	Result   string                     `json:"result"`
+	// Path is the directory the harness imports the submission from
+	Path string `json:"path"`
+}
+
+// functionOutcome is what the harness writes to functionResultFile
+type functionOutcome struct {
+	Value   json.RawMessage `json:"value"`
+	Error   string          `json:"error"`
+	Message string          `json:"message"`
+}
+
+// functionInput is the execution of a function testcase. The submission is imported as the
+// module "submission" by the harness, which calls tc.FunctionName with the arguments from
+// TestcaseInput: a JSON array is passed positionally, a JSON object by keyword.
+func functionInput(submission Submission, tc model.Testcase) (executer.Input, error) {
+	call := functionCall{Module: functionModule, Function: tc.FunctionName, Result: functionResultFile, Path: sourceDir}
+	arguments := strings.TrimSpace(tc.TestcaseInput)
+	switch {
+	case arguments == "":
+	case strings.HasPrefix(arguments, "["):
+		if err := json.Unmarshal([]byte(arguments), &call.Args); err != nil {
+			return executer.Input{}, fmt.Errorf("invalid arguments of testcase %d: %v", tc.TestcaseId, err)
+		}
+	case strings.HasPrefix(arguments, "{"):
+		if err := json.Unmarshal([]byte(arguments), &call.Kwargs); err != nil {
+			return executer.Input{}, fmt.Errorf("invalid arguments of testcase %d: %v", tc.TestcaseId, err)
+		}
+	default:
+		return executer.Input{}, fmt.Errorf("arguments of testcase %d must be a JSON array or object", tc.TestcaseId)
+	}
+	if call.Args == nil {
+		call.Args = []json.RawMessage{}
+	}
+	if call.Kwargs == nil {
+		call.Kwargs = map[string]json.RawMessage{}
+	}
+	if !json.Valid([]byte(tc.TestcaseOutput)) {
+		return executer.Input{}, fmt.Errorf("expected return value of testcase %d is not valid JSON", tc.TestcaseId)
+	}
+	encodedCall, err := json.Marshal(call)
+	if err != nil {
+		return executer.Input{}, err
+	}
+
+	modules, err := harnessModules(submission)
+	if err != nil {
+		return executer.Input{}, err
+	}
+	files := tc.InputFiles()
+	files[functionCallFile] = string(encodedCall)
+
+	input := executer.Input{
+		Code:        functionHarness,
+		Modules:     modules,
+		Files:       files,
+		OutputFiles: []string{functionResultFile},
+	}
+	for _, f := range tc.ExpectedFiles() {
+		input.OutputFiles = append(input.OutputFiles, f.FileName)
+	}
+	return input, nil
+}
+
+// functionOutcomeOf reads what the harness reported for a successful run
+func functionOutcomeOf(tc model.Testcase, result executer.ExecutionResult) functionOutcome {
+	content, ok := result.OutputFiles[functionResultFile]
+	if !ok {
+		return functionOutcome{Error: "no result", Message: fmt.Sprintf("the program exited before %s returned", tc.FunctionName)}
+	}
+	var outcome functionOutcome
+	if err := json.Unmarshal([]byte(content), &outcome); err != nil {
+		return functionOutcome{Error: "no result", Message: fmt.Sprintf("the result of %s could not be read", tc.FunctionName)}
+	}
+	return outcome
+}
+
+// compareReturnValue compares the value the function returned with the expected JSON value
+// of tc. Numbers match within the default tolerances of the numeric comparator; a missing
+// function, a call the signature rejects or an unencodable value fail with their reason.
+func compareReturnValue(tc model.Testcase, result executer.ExecutionResult) Comparison {
+	outcome := functionOutcomeOf(tc, result)
+	if outcome.Error != "" {
+		return Comparison{Reason: outcome.Error + ": " + outcome.Message}
+	}
+
+	var got, want any
+	if err := json.Unmarshal(outcome.Value, &got); err != nil {
+		return Comparison{Reason: fmt.Sprintf("the result of %s could not be read", tc.FunctionName)}
+	}
+	if err := json.Unmarshal([]byte(tc.TestcaseOutput), &want); err != nil {
+		return Comparison{Reason: "the expected return value is not valid JSON"}
+	}
+	if mismatch := compareJSON("return value", got, want); mismatch != "" {
+		return Comparison{Reason: mismatch}
+	}
+	return Comparison{Match: true, Similarity: 1}
+}
+
+// returnValueText is the stored output of a function testcase: what the function printed,
+// then the value it returned
+func returnValueText(tc model.Testcase, result executer.ExecutionResult) string {
+	outcome := functionOutcomeOf(tc, result)
+	if outcome.Error != "" {
+		return result.Stdout
+	}
+	text := tc.FunctionName + " returned " + string(outcome.Value)
+	if result.Stdout == "" {
+		return text
+	}
+	return strings.TrimRight(result.Stdout, "\n") + "\n\n" + text
+}
+
+// compareJSON returns a description of the first difference between two decoded JSON values,
+// located by path, or "" when they are equal
+func compareJSON(path string, got any, want any) string {
+	switch w := want.(type) {
+	case float64:
+		g, ok := got.(float64)
+		if !ok {
+			return fmt.Sprintf("%s: got %s, expected %s", path, describeJSON(got), describeJSON(want))
+		}
+		tolerance := NumericComparator{AbsTolerance: defaultAbsTolerance, RelTolerance: defaultRelTolerance}
+		if !tolerance.withinTolerance(g, w) {
+			return fmt.Sprintf("%s: got %v, expected %v", path, g, w)
+		}
+	case []any:
+		g, ok := got.([]any)
+		if !ok {
+			return fmt.Sprintf("%s: got %s, expected a list", path, describeJSON(got))
+		}
+		if len(g) != len(w) {
+			return fmt.Sprintf("%s: got %d elements, expected %d", path, len(g), len(w))
+		}
+		for i := range w {
+			if mismatch := compareJSON(fmt.Sprintf("%s[%d]", path, i), g[i], w[i]); mismatch != "" {
+				return mismatch
+			}
+		}
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			return fmt.Sprintf("%s: got %s, expected an object", path, describeJSON(got))
+		}
+		keys := make([]string, 0, len(w))
+		for key := range w {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value, ok := g[key]
+			if !ok {
+				return fmt.Sprintf("%s: key %q missing", path, key)
+			}
+			if mismatch := compareJSON(fmt.Sprintf("%s[%q]", path, key), value, w[key]); mismatch != "" {
+				return mismatch
+			}
+		}
+		if len(g) > len(w) {
+			var extra []string
+			for key := range g {
+				if _, ok := w[key]; !ok {
+					extra = append(extra, key)
+				}
+			}
+			sort.Strings(extra)
+			return fmt.Sprintf("%s: unexpected key %q", path, extra[0])
+		}
+	default:
+		if got != want {
+			return fmt.Sprintf("%s: got %s, expected %s", path, describeJSON(got), describeJSON(want))
+		}
+	}
+	return ""
+}
+
+// describeJSON formats a decoded JSON value for a mismatch reason
+func describeJSON(value any) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	if len(encoded) > 60 {
+		return string(encoded[:57]) + "..."
+	}
+	return string(encoded)
+}
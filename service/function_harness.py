@@ -0,0 +1,77 @@
+# Harness of function testcases, run as the main file. It reads the call from CALL_FILE,
+# imports the submission from the directory the call names, calls the function and writes
+# the outcome as JSON to the result file the call names: {"value": ...} for a return value, or
+# {"error": kind, "message": ...} when the function cannot be called or its value not encoded.
+# Exceptions raised by the submission are printed like an uncaught exception in a script.
+# The submission's directory is put on the import path only once the harness has imported
+# what it needs, so no module of the submission can take the place of one of those.
+import inspect
+import json
+import os
+import sys
+import traceback
+
+CALL_FILE = "_harness_call.json"
+
+
+def report(result_file, **outcome):
+    sys.stdout.flush()
+    with open(result_file, "w") as f:
+        json.dump(outcome, f)
+
+
+def fail(error):
+    # Leave out the harness frame so the traceback starts in the submission
+    traceback.print_exception(type(error), error, error.__traceback__.tb_next)
+    sys.exit(1)
+
+
+def main():
+    with open(CALL_FILE) as f:
+        call = json.load(f)
+    os.remove(CALL_FILE)
+    name, args, kwargs, result_file = call["function"], call["args"], call["kwargs"], call["result"]
+
+    sys.argv = [call["module"] + ".py"]
+    sys.path.insert(0, os.path.abspath(call["path"]))
+    try:
+        module = __import__(call["module"])
+    except SyntaxError as error:
+        # Printed without a traceback, as the interpreter does for a script
+        sys.stderr.write("".join(traceback.format_exception_only(type(error), error)))
+        sys.exit(1)
+    except SystemExit:
+        raise
+    except BaseException as error:
+        fail(error)
+
+    function = getattr(module, name, None)
+    if function is None:
+        return report(result_file, error="missing function", message="the submission does not define %s" % name)
+    if not callable(function):
+        return report(result_file, error="not callable", message="%s is a %s, not a function" % (name, type(function).__name__))
+    try:
+        signature = inspect.signature(function)
+    except (TypeError, ValueError):
+        signature = None
+    if signature is not None:
+        try:
+            signature.bind(*args, **kwargs)
+        except TypeError as error:
+            return report(result_file, error="wrong signature", message="%s%s cannot be called with these arguments: %s" % (name, signature, error))
+
+    try:
+        value = function(*args, **kwargs)
+    except SystemExit:
+        raise
+    except BaseException as error:
+        fail(error)
+
+    try:
+        json.dumps(value, allow_nan=False)
+    except (TypeError, ValueError) as error:
+        return report(result_file, error="unencodable result", message="%s returned a %s, which cannot be encoded as JSON: %s" % (name, type(value).__name__, error))
+    report(result_file, value=value)
+
+
+main()
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+// TestFunctionTestcases runs function testcases through the harness and checks how the
+// return value, a failed call and errors raised by the submission are judged
+func TestFunctionTestcases(t *testing.T) {
+	code := `
+import helper
+
+def solve(a, b=0):
+    print("adding")
+    return {"sum": helper.add(a, b), "items": [a, b]}
+
+def crash():
+    return 1 / 0
+
+def unencodable():
+    return {1, 2}
+
+value = 3
+`
+	submission := Submission{Code: code, Modules: map[string]string{"helper.py": "def add(a, b):\n    return a + b\n"}}
+
+	cases := []struct {
+		name     string
+		code     string
+		function string
+		args     string
+		want     string
+		verdict  model.Verdict
+		reason   string
+	}{
+		{"positional", code, "solve", "[1, 2.5]", `{"items": [1, 2.5], "sum": 3.5}`, model.VerdictAccepted, ""},
+		{"keywords", code, "solve", `{"a": 2}`, `{"items": [2, 0], "sum": 2}`, model.VerdictAccepted, ""},
+		{"wrong value", code, "solve", "[1, 2]", `{"items": [1, 2], "sum": 4}`, model.VerdictWrongAnswer, `return value["sum"]: got 3, expected 4`},
+		{"missing function", code, "solve2", "[]", "0", model.VerdictWrongAnswer, "missing function: the submission does not define solve2"},
+		{"not callable", code, "value", "[]", "3", model.VerdictWrongAnswer, "not callable: value is a int, not a function"},
+		{"wrong signature", code, "solve", "[1, 2, 3]", "0", model.VerdictWrongAnswer, "wrong signature: solve(a, b=0) cannot be called"},
+		{"unencodable", code, "unencodable", "[]", "[1, 2]", model.VerdictWrongAnswer, "unencodable result: unencodable returned a set"},
+		{"exception", code, "crash", "[]", "1", model.VerdictRuntimeError, "ZeroDivisionError"},
+		{"syntax error", "def solve(:\n", "solve", "[]", "1", model.VerdictCompileError, "SyntaxError"},
+	}
+	runner := &executer.PythonExecutor{}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tc := model.Testcase{FunctionName: c.function, TestcaseInput: c.args, TestcaseOutput: c.want}
+			input, err := testcaseInput(Submission{Code: c.code, Modules: submission.Modules}, tc)
+			if err != nil {
+				t.Fatalf("testcaseInput: %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			result, err := runner.Run(ctx, input)
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+
+			var verdict model.Verdict
+			reason := result.Stderr
+			if result.Succeeded() {
+				comparison := compareOutputs(nil, nil, tc.TestcaseOutput, tc, result)
+				verdict, reason = comparisonVerdict(comparison), comparison.Reason
+			} else {
+				verdict = runVerdict(result)
+			}
+			if verdict != c.verdict || !strings.Contains(reason, c.reason) {
+				t.Fatalf("got %s %q, expected %s containing %q", verdict, reason, c.verdict, c.reason)
+			}
+			if c.name == "positional" && returnValueText(tc, result) != "adding\n\nsolve returned {\"sum\": 3.5, \"items\": [1, 2.5]}" {
+				t.Fatalf("unexpected output text %q", returnValueText(tc, result))
+			}
+			if c.verdict == model.VerdictRuntimeError && strings.Contains(reason, "main.py") {
+				t.Fatalf("traceback shows the harness: %s", reason)
+			}
+		})
+	}
+}
+
+// TestFunctionHarnessShadowing checks that modules of the submission named like those the
+// harness imports do not replace them
+func TestFunctionHarnessShadowing(t *testing.T) {
+	const forged = "import os\nopen('_harness_result.json', 'w').write('{\"value\": 4}')\nos._exit(0)\n"
+	submission := Submission{Code: "def solve():\n    return 3\n", Modules: map[string]string{"json.py": forged, "inspect.py": forged}}
+	tc := model.Testcase{FunctionName: "solve", TestcaseInput: "[]", TestcaseOutput: "4"}
+	input, err := testcaseInput(submission, tc)
+	if err != nil {
+		t.Fatalf("testcaseInput: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := (&executer.PythonExecutor{}).Run(ctx, input)
+	if err != nil || !result.Succeeded() {
+		t.Fatalf("run failed: %+v (%v)", result, err)
+	}
+	if comparison := compareReturnValue(tc, result); comparison.Match || !strings.Contains(comparison.Reason, "got 3, expected 4") {
+		t.Fatalf("unexpected comparison %+v", comparison)
+	}
+}
+
+// TestFunctionInput rejects arguments that are neither a JSON array nor an object
+func TestFunctionInput(t *testing.T) {
+	for _, args := range []string{"1, 2", "[1,", `"a"`} {
+		tc := model.Testcase{FunctionName: "solve", TestcaseInput: args, TestcaseOutput: "1"}
+		if _, err := functionInput(Submission{Code: "pass"}, tc); err == nil {
+			t.Errorf("expected an error for arguments %q", args)
+		}
+	}
+	tc := model.Testcase{FunctionName: "solve", TestcaseInput: "[]", TestcaseOutput: "1"}
+	if _, err := functionInput(Submission{Code: "pass", Modules: map[string]string{"submission.py": ""}}, tc); err == nil {
+		t.Error("expected an error for a module clashing with the submission")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"python-runner/executer"
 	"python-runner/model"
 	"strconv"
@@ -14,12 +15,16 @@ import (
 )
 
 func GradeFileByOldId(ctx context.Context, file string) error {
+	return GradeFileByOldIdWithOptions(ctx, file, Options{})
+}
+
+func GradeFileByOldIdWithOptions(ctx context.Context, file string, opts Options) error {
 	if file == "" {
 		return fmt.Errorf("--file must be provided")
 	}
 
-	// extract file name from path
-	filename := strings.Split(file, "/")[len(strings.Split(file, "/"))-1]
+	// extract file name from path; a bundle directory is named like a file without extension
+	filename := filepath.Base(filepath.Clean(file))
 	oldIdStr := strings.Split(strings.Split(filename, ".")[0], "_")[0]
 	var versionId int
 	var err error
@@ -30,8 +35,7 @@ func GradeFileByOldId(ctx context.Context, file string) error {
 		}
 	}
 
-	var sourceCode string
-	sourceCode, err = ReadSourceCodeFromFile(file)
+	submission, err := ReadSubmission(file)
 	if err != nil {
 		return fmt.Errorf("failed to read source code from file: %v", err.Error())
 	}
@@ -40,32 +44,74 @@ func GradeFileByOldId(ctx context.Context, file string) error {
 	if err != nil {
 		return fmt.Errorf("failed to parse oldId from filename: %v", err.Error())
 	}
-	return Grade(ctx, oldId, versionId, sourceCode)
+	return GradeSubmission(ctx, oldId, versionId, submission, opts)
 }
 
 func Grade(ctx context.Context, oldId int, versionId int, sourceCode string) error {
-	// Add overall timeout for the entire grading process
-	gradeCtx, gradeCancel := context.WithTimeout(ctx, time.Minute*2)
-	defer gradeCancel()
+	return GradeWithOptions(ctx, oldId, versionId, sourceCode, Options{})
+}
+
+func GradeWithOptions(ctx context.Context, oldId int, versionId int, sourceCode string, opts Options) error {
+	return GradeSubmission(ctx, oldId, versionId, Submission{Code: sourceCode}, opts)
+}
 
+// GradeSubmission grades a submission that may consist of several files. The main file is
+// stored as the submission's source code and its modules alongside it.
+func GradeSubmission(ctx context.Context, oldId int, versionId int, submission Submission, opts Options) error {
 	mysqlExecuter := executer.NewMySQLExecuter()
-	python := &executer.PythonExecutor{}
 
 	// Add timeout for database operations
-	dbCtx, dbCancel := context.WithTimeout(gradeCtx, time.Second*30)
+	dbCtx, dbCancel := context.WithTimeout(ctx, time.Second*30)
 	codeInfo, err := mysqlExecuter.GetSourceCodeInfoWithContext(dbCtx, oldId)
 	dbCancel()
 	if err != nil {
 		return fmt.Errorf("failed to get source code info for old ID %d: %v", oldId, err.Error())
 	}
 
-	dbCtx2, dbCancel2 := context.WithTimeout(gradeCtx, time.Second*30)
+	dbCtx2, dbCancel2 := context.WithTimeout(ctx, time.Second*30)
 	testcases, err := mysqlExecuter.GetTestCasesWithContext(dbCtx2, codeInfo.QuestionId)
 	dbCancel2()
 	if err != nil {
 		return fmt.Errorf("failed to get test cases for question ID %d: %v", codeInfo.QuestionId, err.Error())
 	}
 
+	dbCtx3, dbCancel3 := context.WithTimeout(ctx, time.Second*30)
+	question, err := mysqlExecuter.GetQuestionWithContext(dbCtx3, codeInfo.QuestionId)
+	dbCancel3()
+	if err != nil {
+		return fmt.Errorf("failed to get question %d: %v", codeInfo.QuestionId, err.Error())
+	}
+
+	// Each testcase runs on an executor for its limits; the question's limits bound
+	// everything else that runs the submission
+	defaults := executer.DefaultLimits()
+	questionLimits := resolveLimits(defaults, question, model.Testcase{}, opts)
+	limits := map[int]testLimits{}
+	allLimits := []testLimits{questionLimits}
+	for _, tc := range testcases {
+		tcLimits := resolveLimits(defaults, question, tc, opts)
+		limits[tc.TestcaseId] = tcLimits
+		allLimits = append(allLimits, tcLimits)
+	}
+	runners, err := newRunners(question, opts, defaults, allLimits)
+	if err != nil {
+		return fmt.Errorf("failed to select executor for question %d: %v", codeInfo.QuestionId, err.Error())
+	}
+	runner := runners[questionLimits]
+
+	checker, err := newChecker(question, opts, defaults, allLimits)
+	if err != nil {
+		return fmt.Errorf("failed to set up the checker of question %d: %v", codeInfo.QuestionId, err.Error())
+	}
+	interactor, err := newInteractor(question, opts, defaults, allLimits)
+	if err != nil {
+		return fmt.Errorf("failed to set up the interactor of question %d: %v", codeInfo.QuestionId, err.Error())
+	}
+	efficiency, err := newEfficiency(question, opts)
+	if err != nil {
+		return fmt.Errorf("failed to set up efficiency scoring of question %d: %v", codeInfo.QuestionId, err.Error())
+	}
+
 	if versionId == 0 {
 		versionId = codeInfo.Version
 	}
@@ -73,7 +119,7 @@ func Grade(ctx context.Context, oldId int, versionId int, sourceCode string) err
 		StudentQuestionFileId: codeInfo.StudentQuestionFileId,
 		UserId:                codeInfo.UserId,
 		QuestionId:            codeInfo.QuestionId,
-		SourceCode:            sourceCode,
+		SourceCode:            submission.Code,
 		Version:               versionId,
 		Score:                 0,
 		Status:                "N",
@@ -84,61 +130,109 @@ func Grade(ctx context.Context, oldId int, versionId int, sourceCode string) err
 	}
 
 	newSourceCodeInfo.StudentQuestionFileV2Id = newSourceCodeInfoId
+	if err := mysqlExecuter.InsertSourceFilesV2(submission.sourceFiles(newSourceCodeInfoId)); err != nil {
+		return fmt.Errorf("failed to insert submission files: %v", err.Error())
+	}
 
-	// Process test cases with timeout protection
+	g := &grading{
+		sourceCodeId: newSourceCodeInfoId,
+		runner:       runner,
+		checker:      checker,
+		interactor:   interactor,
+		efficiency:   efficiency,
+		question:     question,
+		submission:   submission,
+		limits:       limits,
+		runners:      runners,
+	}
+	slots := opts.slots
+	if slots == nil {
+		slots = newRunSlots(opts.ParallelTestcases)
+	}
+	if !slots.acquire(ctx) {
+		return recordResults(mysqlExecuter, newSourceCodeInfo, oldId, g.ungradedResults(testcases, ctx.Err()), ctx.Err())
+	}
+	var suiteTime time.Duration
+	var suiteTests []testLimits
 	for _, tc := range testcases {
-		// Check if main context is cancelled
-		select {
-		case <-gradeCtx.Done():
-			return fmt.Errorf("grading cancelled for old ID %d: %v", oldId, gradeCtx.Err())
-		default:
+		if tc.TestName != "" {
+			suiteTime += limits[tc.TestcaseId].timeout()
+			suiteTests = append(suiteTests, limits[tc.TestcaseId])
 		}
-
-		testResult := model.TestcaseResult{
-			StudentQuestionFileV2Id: newSourceCodeInfoId,
-			TestcaseId:              tc.TestcaseId,
-			Score:                   int(tc.Score),
-			Status:                  "N",
-			TestOutputText:          "",
+	}
+	// The checks before the testcases, and the test suite among them, get their time once
+	// they hold a slot; each testcase gets its own as it runs
+	checkCtx, checkCancel := context.WithTimeout(ctx, submissionMargin+suiteTime)
+	defer checkCancel()
+
+	// A submission that does not compile fails every testcase the same way, so none is run
+	if failed := checkSyntax(checkCtx, runner, submission, questionLimits); failed != nil {
+		slots.release()
+		results := compileErrorResults(newSourceCodeInfoId, testcases, *failed)
+		for i := range results {
+			limits[results[i].TestcaseId].record(&results[i])
 		}
-
-		// Create separate timeout for each test case execution
-		testCtx, testCancel := context.WithTimeout(gradeCtx, time.Second*10)
-		output, err := python.Execute(testCtx, sourceCode, tc.TestcaseInput)
-		testCancel() // Always cancel to free resources
-
-		var match bool = false
-		var similarity float32 = 0
-		if err != nil && output == "" {
-			testResult.TestOutputText = err.Error()
-			match = false
-			similarity = 0
-		} else {
-			testResult.TestOutputText = output
-			output = strings.TrimSpace(output)
-			expected := strings.TrimSpace(tc.TestcaseOutput)
-			match, similarity = compareResult(output, expected)
+		if err := mysqlExecuter.InsertTestRunResultsV2(results); err != nil {
+			return fmt.Errorf("failed to insert compile error results: %v", err.Error())
+		}
+		verdicts := make([]model.Verdict, len(results))
+		for i, r := range results {
+			verdicts[i] = r.Verdict
 		}
-		if match {
-			testResult.Status = "P"
+		return finishSubmission(mysqlExecuter, newSourceCodeInfo, verdicts)
+	}
+
+	// Static rules are checked once, before anything runs
+	g.static = checkStaticRules(checkCtx, runner, question, submission, questionLimits)
+
+	if g.static.verdict == "" && suiteTime > 0 {
+		// The suite runs once, with the time of all the testcases it grades
+		suiteRunner, err := newRunner(question, opts, suiteLimits(defaults, questionLimits, suiteTests))
+		if err != nil {
+			g.suite = &suiteRun{err: err}
 		} else {
-			testResult.Status = "F"
-			testResult.Score = int(float32(testResult.Score) * similarity)
+			g.suite = runTestSuite(checkCtx, suiteRunner, submission, question)
 		}
+	}
+	slots.release()
+	checkCancel()
 
-		err = mysqlExecuter.InsertTestRunResultV2(testResult)
+	results, cancelled := g.gradeTestcases(ctx, testcases, opts.ParallelTestcases, slots)
+	return recordResults(mysqlExecuter, newSourceCodeInfo, oldId, results, cancelled)
+}
+
+// recordResults stores the results, in the order of the testcases however they ran, and
+// the final score of the submission. A grading that was cancelled is finished all the same
+// and then reported with the error that cancelled it.
+func recordResults(mysqlExecuter *executer.MySQLExecuter, newSourceCodeInfo model.SourceCode, oldId int, results []model.TestcaseResult, cancelled error) error {
+	var verdicts []model.Verdict
+	for _, testResult := range results {
+		verdicts = append(verdicts, testResult.Verdict)
+
+		err := mysqlExecuter.InsertTestRunResultV2(testResult)
 		if err != nil {
-			fmt.Printf("Error inserting test result: testcase %d, ErrorMessage: %v\n", tc.TestcaseId, err.Error())
+			fmt.Printf("Error inserting test result: testcase %d, ErrorMessage: %v\n", testResult.TestcaseId, err.Error())
 			continue
 		}
 	}
+	if err := finishSubmission(mysqlExecuter, newSourceCodeInfo, verdicts); err != nil {
+		return err
+	}
+	if cancelled != nil {
+		return fmt.Errorf("grading cancelled for old ID %d, its unjudged testcases were recorded as internal errors: %v", oldId, cancelled)
+	}
+	return nil
+}
 
-	finalScore, err := mysqlExecuter.CalculateSourceCodeScoreV2(newSourceCodeInfoId, codeInfo.QuestionId)
+// finishSubmission records the final score and verdict of a graded submission
+func finishSubmission(mysqlExecuter *executer.MySQLExecuter, newSourceCodeInfo model.SourceCode, verdicts []model.Verdict) error {
+	finalScore, err := mysqlExecuter.CalculateSourceCodeScoreV2(newSourceCodeInfo.StudentQuestionFileV2Id, newSourceCodeInfo.QuestionId)
 	if err != nil {
 		return fmt.Errorf("failed to calculate final score: %v", err.Error())
 	}
 	// update sourceCode info v2 with final score
 	newSourceCodeInfo.Score = finalScore
+	newSourceCodeInfo.Verdict = model.AggregateVerdict(verdicts)
 	err = mysqlExecuter.UpdateSourceCodeAtV2(newSourceCodeInfo)
 	if err != nil {
 		return fmt.Errorf("failed to update source code with final score: %v", err.Error())
@@ -146,38 +240,50 @@ func Grade(ctx context.Context, oldId int, versionId int, sourceCode string) err
 	return nil
 }
 
-func compareResult(got string, want string) (bool, float32) {
-
-	// replace all \r\n with \n
-	var similarity float32 = 0
-	got = strings.ReplaceAll(got, "\r\n", "\n")
-	want = strings.ReplaceAll(want, "\r\n", "\n")
-
-	gotLines := strings.Split(strings.TrimSpace(got), "\n")
-	wantLines := strings.Split(strings.TrimSpace(want), "\n")
+// runTestcase runs the submission for one testcase and judges the run, with the question's
+// checker when it has one, returning the verdict, the output to store with the result, the
+// similarity that scales a partial score and the run itself, for the time and memory it
+// took. The run is stopped by the limits of runner, and the checker runs under limits.
+func runTestcase(ctx context.Context, runner executer.Executor, checker *Checker, question model.Question, submission Submission, tc model.Testcase, limits testLimits) (model.Verdict, string, float32, executer.ExecutionResult) {
+	comparator, expected, err := selectComparator(question, tc)
+	if err != nil {
+		return model.VerdictInternalError, fmt.Sprintf("%s: %v", model.VerdictInternalError, err), 0, executer.ExecutionResult{}
+	}
 
-	min_len := len(gotLines)
-	if len(wantLines) < min_len {
-		min_len = len(wantLines)
+	// The runner stops the run at the time limit of the testcase
+	var result executer.ExecutionResult
+	input, err := testcaseInput(submission, tc)
+	if err == nil && tc.FunctionName != "" && runner.Name() != "python" {
+		err = fmt.Errorf("function testcases are only supported for python, not %s", runner.Name())
+	}
+	if err == nil {
+		result, err = runner.Run(ctx, input)
 	}
 
-	for i := 0; i < min_len; i++ {
-		gotLine := normalizeLine(gotLines[i])
-		wantLine := normalizeLine(wantLines[i])
-		// compare
-		if gotLine == wantLine {
-			similarity += 1.0
-		}
+	if err != nil {
+		// The grader itself failed to run the submission
+		return model.VerdictInternalError, fmt.Sprintf("%s: %v", model.VerdictInternalError, err), 0, result
+	}
+	if !result.Succeeded() {
+		verdict := runVerdict(result)
+		return verdict, describeFailedRun(verdict, result), 0, result
 	}
-	return similarity/float32(len(wantLines)) == 1.0, similarity / float32(len(wantLines))
-}
 
-func normalizeLine(s string) string {
-	s = strings.ReplaceAll(s, ":", "")
-	s = strings.ReplaceAll(s, " ", "")
-	s = strings.TrimSpace(s)
-	s = strings.ToLower(s)
-	return s
+	stdout := comparator
+	var checked *checkerComparator
+	if checker != nil && tc.FunctionName == "" {
+		checked = &checkerComparator{ctx: ctx, checker: checker, limits: limits, input: tc.TestcaseInput}
+		stdout, expected = checked, tc.TestcaseOutput
+	}
+	comparison := compareOutputs(stdout, comparator, expected, tc, result)
+	if checked != nil && checked.err != nil {
+		return model.VerdictInternalError, appendJudgeNote(result.Stdout, checked.err.Error()), 0, result
+	}
+	output := result.Stdout
+	if tc.FunctionName != "" {
+		output = returnValueText(tc, result)
+	}
+	return comparisonVerdict(comparison), appendJudgeNote(output, comparison.Reason), comparison.Similarity, result
 }
 
 func ReadSourceCodeFromFile(file string) (string, error) {
@@ -197,6 +303,10 @@ func GradeFilesFromIdsCSV(csvfile string, latestVersionDir string, olderVersionD
 }
 
 func GradeFilesFromIdsCSVWithWorkers(csvfile string, latestVersionDir string, olderVersionDir string, maxWorkers int) error {
+	return GradeFilesFromIdsCSVWithOptions(csvfile, latestVersionDir, olderVersionDir, maxWorkers, Options{})
+}
+
+func GradeFilesFromIdsCSVWithOptions(csvfile string, latestVersionDir string, olderVersionDir string, maxWorkers int, opts Options) error {
 	if csvfile == "" {
 		return fmt.Errorf("--csvfile must be provided")
 	}
@@ -234,6 +344,10 @@ func GradeFilesFromIdsCSVWithWorkers(csvfile string, latestVersionDir string, ol
 
 	fmt.Printf("Processing %d valid IDs with %d workers\n", len(validIds), maxWorkers)
 
+	// Programs of every submission, and of every testcase run in parallel within one, share
+	// maxWorkers slots
+	opts.slots = newRunSlots(maxWorkers)
+
 	// Create job channels
 	latestVersionJobs := make(chan int, len(validIds))
 	olderVersionJobs := make(chan int, len(validIds))
@@ -252,9 +366,8 @@ func GradeFilesFromIdsCSVWithWorkers(csvfile string, latestVersionDir string, ol
 		go func(workerID int) {
 			defer latestWg.Done()
 			for oldId := range latestVersionJobs {
-				ctx, cancelFunc := context.WithTimeout(context.Background(), time.Minute*3) // Increase timeout
-				processLatestVersionFile(ctx, oldId, latestVersionDir)
-				cancelFunc()
+				// Each submission is bounded by the time limits of its question
+				processLatestVersionFile(context.Background(), oldId, latestVersionDir, opts)
 
 				// Update progress
 				completed := atomic.AddInt64(&completedCount, 1)
@@ -271,9 +384,7 @@ func GradeFilesFromIdsCSVWithWorkers(csvfile string, latestVersionDir string, ol
 		go func(workerID int) {
 			defer olderWg.Done()
 			for oldId := range olderVersionJobs {
-				ctx, cancelFunc := context.WithTimeout(context.Background(), time.Minute*5) // Longer timeout for multiple files
-				processOlderVersionFiles(ctx, oldId, olderVersionDir)
-				cancelFunc()
+				processOlderVersionFiles(context.Background(), oldId, olderVersionDir, opts)
 			}
 		}(i)
 	}
@@ -296,19 +407,26 @@ func GradeFilesFromIdsCSVWithWorkers(csvfile string, latestVersionDir string, ol
 	return nil
 }
 
-// processLatestVersionFile searches for and processes the latest version file ("<id>.py") in the specified directory
-func processLatestVersionFile(ctx context.Context, oldId int, latestVersionDir string) {
-	latestVersionFile := fmt.Sprintf("%s/%d.py", latestVersionDir, oldId)
-	if _, err := os.Stat(latestVersionFile); err == nil {
-		err := GradeFileByOldId(ctx, latestVersionFile)
-		if err != nil {
-			fmt.Printf("Error grading latest version file %s: %v\n", latestVersionFile, err)
+// processLatestVersionFile searches for and processes the latest version file ("<id>.py"),
+// or bundle directory ("<id>"), in the specified directory
+func processLatestVersionFile(ctx context.Context, oldId int, latestVersionDir string, opts Options) {
+	for _, latestVersionFile := range []string{
+		fmt.Sprintf("%s/%d.py", latestVersionDir, oldId),
+		fmt.Sprintf("%s/%d", latestVersionDir, oldId),
+	} {
+		if _, err := os.Stat(latestVersionFile); err == nil {
+			err := GradeFileByOldIdWithOptions(ctx, latestVersionFile, opts)
+			if err != nil {
+				fmt.Printf("Error grading latest version file %s: %v\n", latestVersionFile, err)
+			}
+			return
 		}
 	}
 }
 
-// processOlderVersionFiles searches for and processes older version files ("<id>_<version>.py") in the specified directory
-func processOlderVersionFiles(ctx context.Context, oldId int, olderVersionDir string) {
+// processOlderVersionFiles searches for and processes older version files ("<id>_<version>.py"),
+// or bundle directories ("<id>_<version>"), in the specified directory
+func processOlderVersionFiles(ctx context.Context, oldId int, olderVersionDir string, opts Options) {
 	files, err := os.ReadDir(olderVersionDir)
 	if err != nil {
 		fmt.Printf("Error reading older version directory %s: %v\n", olderVersionDir, err)
@@ -316,19 +434,16 @@ func processOlderVersionFiles(ctx context.Context, oldId int, olderVersionDir st
 	}
 
 	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
 		filename := file.Name()
-		// Check if file matches pattern "<id>_<version>.py"
-		if strings.HasSuffix(filename, ".py") {
+		// Check if file matches pattern "<id>_<version>.py", or is a bundle "<id>_<version>"
+		if strings.HasSuffix(filename, ".py") != file.IsDir() {
 			filePrefix := strings.TrimSuffix(filename, ".py")
 			parts := strings.Split(filePrefix, "_")
 			if len(parts) == 2 {
 				fileOldId, err := strconv.Atoi(parts[0])
 				if err == nil && fileOldId == oldId {
 					olderVersionFile := fmt.Sprintf("%s/%s", olderVersionDir, filename)
-					err := GradeFileByOldId(ctx, olderVersionFile)
+					err := GradeFileByOldIdWithOptions(ctx, olderVersionFile, opts)
 					if err != nil {
 						fmt.Printf("Error grading older version file %s: %v\n", olderVersionFile, err)
 					}
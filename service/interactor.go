@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+// interactorVerdictFile is where the interactor writes its decision, in the format of a
+// checker's output, since its stdout belongs to the submission
+const interactorVerdictFile = "verdict.txt"
+
+// Interactor is the judge of interactive questions: a program the submission talks to,
+// its stdout connected to the submission's stdin and the other way round. It finds the
+// testcase in input.txt and expected.txt and writes its decision to verdict.txt:
+//
+//	AC | WA | PA <fraction>
+//	<message, any number of lines>
+//
+// It runs on the interpreter the question's submissions run on, under the same limits as
+// the submission for the testcase, and shares its time limit with the submission.
+type Interactor struct {
+	// runners run the interactor under each of the limits of the question's testcases
+	runners map[testLimits]executer.Executor
+	code    string
+}
+
+// newInteractor returns the interactor of the question, or nil when it has none. It can
+// judge testcases with any of limits, applied to defaults.
+func newInteractor(question model.Question, opts Options, defaults executer.Limits, limits []testLimits) (*Interactor, error) {
+	if question.Interactor == "" {
+		return nil, nil
+	}
+	program := model.Question{Language: question.InteractorLanguage, Interpreter: question.Interpreter}
+	runners, err := newRunners(program, opts, defaults, limits)
+	if err != nil {
+		return nil, fmt.Errorf("interactor: %v", err)
+	}
+	return &Interactor{runners: runners, code: question.Interactor}, nil
+}
+
+// judge runs the submission against the interactor for tc, which has limits, returning the
+// verdict, the stored output, the similarity and the submission's run like runTestcase. A
+// rejection by the interactor wins over the way the submission ended, which may only be a
+// consequence of it, and so does an interactor that failed or gave no decision, which is an
+// internal error, unless it timed out waiting for a submission that failed. Otherwise a
+// failed submission takes its run verdict.
+func (i *Interactor) judge(ctx context.Context, runner executer.Executor, submission Submission, tc model.Testcase, limits testLimits) (model.Verdict, string, float32, executer.ExecutionResult) {
+	interactor, ok := i.runners[limits]
+	if !ok {
+		return model.VerdictInternalError, fmt.Sprintf("%s: interactor has no executor for the limits %+v", model.VerdictInternalError, limits), 0, executer.ExecutionResult{}
+	}
+	input, err := testcaseInput(submission, tc)
+	if err != nil {
+		return model.VerdictInternalError, fmt.Sprintf("%s: %v", model.VerdictInternalError, err), 0, executer.ExecutionResult{}
+	}
+	input.Stdin = ""
+	interactorInput := executer.Input{
+		Code: i.code,
+		Files: map[string]string{
+			checkerInputFile:    tc.TestcaseInput,
+			checkerExpectedFile: tc.TestcaseOutput,
+		},
+		OutputFiles: []string{interactorVerdictFile},
+	}
+
+	testCtx, testCancel := context.WithTimeout(ctx, limits.timeout())
+	interaction, err := executer.Interact(testCtx, runner, input, interactor, interactorInput)
+	testCancel()
+	if err != nil {
+		return model.VerdictInternalError, fmt.Sprintf("%s: %v", model.VerdictInternalError, err), 0, executer.ExecutionResult{}
+	}
+
+	result := interaction.Program
+	decision, decisionErr := interactorDecision(interaction.Interactor)
+	if decisionErr == nil && !decision.Match {
+		verdict := comparisonVerdict(decision)
+		return verdict, appendJudgeNote(result.Stdout, decision.Reason), decision.Similarity, result
+	}
+	if decisionErr != nil && (result.Succeeded() || !interaction.Interactor.TimedOut) {
+		return model.VerdictInternalError, appendJudgeNote(result.Stdout, decisionErr.Error()), 0, result
+	}
+	if !result.Succeeded() {
+		verdict := runVerdict(result)
+		return verdict, describeFailedRun(verdict, result), 0, result
+	}
+	return model.VerdictAccepted, appendJudgeNote(result.Stdout, decision.Reason), 1, result
+}
+
+// interactorDecision reads the decision the interactor wrote
+func interactorDecision(result executer.ExecutionResult) (Comparison, error) {
+	content, ok := result.OutputFiles[interactorVerdictFile]
+	if !result.Succeeded() {
+		message := "interactor failed"
+		if err := result.Err(); err != nil {
+			message += ": " + err.Error()
+		}
+		if ok {
+			// A decision written before the interactor failed still stands
+			if decision, err := parseCheckerOutput(content); err == nil {
+				return decision, nil
+			}
+		}
+		return Comparison{}, fmt.Errorf("%s", message)
+	}
+	if !ok {
+		return Comparison{}, fmt.Errorf("interactor wrote no %s", interactorVerdictFile)
+	}
+	decision, err := parseCheckerOutput(content)
+	if err != nil {
+		return Comparison{}, fmt.Errorf("interactor: %v", err)
+	}
+	return decision, nil
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+// guessingInteractor lets the submission guess the number in input.txt with at most 7 guesses
+const guessingInteractor = `
+secret = int(open("input.txt").read())
+for guesses in range(1, 8):
+    try:
+        guess = int(input())
+    except (EOFError, ValueError):
+        break
+    if guess == secret:
+        print("=")
+        open("verdict.txt", "w").write("AC\nfound in %d guesses" % guesses)
+        raise SystemExit
+    print("<" if secret < guess else ">")
+open("verdict.txt", "w").write("WA\nthe number was not found")
+`
+
+const binarySearch = `
+lo, hi = 1, 100
+while True:
+    mid = (lo + hi) // 2
+    print(mid)
+    answer = input()
+    if answer == "=":
+        break
+    lo, hi = (mid + 1, hi) if answer == ">" else (lo, mid - 1)
+`
+
+// TestInteractor judges submissions of a guessing game by its interactor
+func TestInteractor(t *testing.T) {
+	cases := []struct {
+		name       string
+		interactor string
+		code       string
+		verdict    model.Verdict
+		output     string
+	}{
+		{"binary search", guessingInteractor, binarySearch, model.VerdictAccepted, "[judge] found in 7 guesses"},
+		{"linear search", guessingInteractor, "for i in range(1, 101):\n    print(i)\n    if input() == '=':\n        break\n", model.VerdictWrongAnswer, "[judge] the number was not found"},
+		{"crash", guessingInteractor, "print(50)\ninput()\nraise ValueError('lost')\n", model.VerdictWrongAnswer, "the number was not found"},
+		{"crash after success", guessingInteractor, binarySearch + "raise ValueError('late')\n", model.VerdictRuntimeError, "ValueError: late"},
+		{"no decision", "input()\n", binarySearch, model.VerdictInternalError, "interactor wrote no verdict.txt"},
+		{"interactor crash", "raise SystemExit(3)\n", binarySearch, model.VerdictInternalError, "interactor failed"},
+		{"deadlock", "input()\n", "input()\n", model.VerdictTimeLimit, "Time Limit"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			limits := testLimits{Time: defaultTimeLimit}
+			interactor := &Interactor{runners: map[testLimits]executer.Executor{limits: &executer.PythonExecutor{}}, code: c.interactor}
+			tc := model.Testcase{TestcaseInput: "42"}
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			verdict, output, _, _ := interactor.judge(ctx, &executer.PythonExecutor{}, Submission{Code: c.code}, tc, limits)
+			if verdict != c.verdict || !strings.Contains(output, c.output) {
+				t.Fatalf("got %s with output:\n%s\nexpected %s containing %q", verdict, output, c.verdict, c.output)
+			}
+		})
+	}
+}
+
+// TestNewInteractor checks that the interactor runs on the interpreter configured for the
+// question, under the limits of the testcase it judges
+func TestNewInteractor(t *testing.T) {
+	question := model.Question{Interactor: "print('AC')"}
+	limits := testLimits{Time: 2 * time.Second, MemoryMB: 64}
+	interactor, err := newInteractor(question, Options{Interpreter: "python3"}, executer.Limits{}, []testLimits{limits})
+	if err != nil {
+		t.Fatalf("newInteractor: %v", err)
+	}
+	python, ok := interactor.runners[limits].(*executer.PythonExecutor)
+	if !ok || python.Interpreter != "python3" {
+		t.Fatalf("interactor does not run on the configured interpreter: %+v", interactor.runners)
+	}
+	if python.Limits != limits.apply(executer.Limits{}) {
+		t.Fatalf("interactor does not run under the limits of the testcase: %+v", python.Limits)
+	}
+	if _, err := newInteractor(question, Options{Interpreter: "python0"}, executer.Limits{}, []testLimits{limits}); err == nil {
+		t.Fatalf("newInteractor should fail for an interpreter that is not configured")
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"time"
+
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+const (
+	// defaultTimeLimit is the time a run of the submission gets when its question sets none
+	defaultTimeLimit = 10 * time.Second
+	// submissionMargin is the time the checks before the testcases of a submission run get
+	// on top of the time limits of the runs among them
+	submissionMargin = time.Minute
+	// testcaseMargin is the time a testcase gets on top of the time limits of its runs, for
+	// starting them and judging their output
+	testcaseMargin = 5 * time.Second
+)
+
+// testLimits are the time and memory a run of the submission for a testcase may use. They
+// are recorded with the result, so the same limits grade the submission again.
+type testLimits struct {
+	// Time is the wall time of the run
+	Time time.Duration
+	// MemoryMB bounds the address space of the run; 0 leaves it unlimited
+	MemoryMB int
+}
+
+// resolveLimits returns the limits of tc: a limit set for the run by the options comes
+// first, then the testcase's, then the question's, and then the configured default.
+// The zero Testcase gives the limits of the question.
+func resolveLimits(defaults executer.Limits, question model.Question, tc model.Testcase, opts Options) testLimits {
+	limits := testLimits{Time: defaultTimeLimit, MemoryMB: int(defaults.AddressSpace >> 20)}
+	for _, l := range []testLimits{
+		{time.Duration(question.TimeLimitMs) * time.Millisecond, question.MemoryLimitMB},
+		{time.Duration(tc.TimeLimitMs) * time.Millisecond, tc.MemoryLimitMB},
+		{opts.TimeLimit, opts.MemoryLimitMB},
+	} {
+		if l.Time > 0 {
+			limits.Time = l.Time
+		}
+		if l.MemoryMB > 0 {
+			limits.MemoryMB = l.MemoryMB
+		}
+	}
+	return limits
+}
+
+// timeout is the wall time of a run, the default when no limit was resolved
+func (l testLimits) timeout() time.Duration {
+	if l.Time <= 0 {
+		return defaultTimeLimit
+	}
+	return l.Time
+}
+
+// apply returns the executor limits for a run under l. The time limit bounds the wall
+// time of the run, which the CPU time never exceeds, and memory replaces the configured
+// address space.
+func (l testLimits) apply(limits executer.Limits) executer.Limits {
+	limits.WallTime = l.timeout()
+	if limits.CPUTime <= 0 || limits.CPUTime > l.timeout() {
+		limits.CPUTime = l.timeout()
+	}
+	limits.AddressSpace = int64(l.MemoryMB) << 20
+	return limits
+}
+
+// suiteLimits returns the executor limits of the single run of a test suite that grades
+// testcases with the limits tests. It gets the CPU time of all of them, each bounded as
+// for a run of its own, and the memory of the question.
+func suiteLimits(defaults executer.Limits, question testLimits, tests []testLimits) executer.Limits {
+	var suite testLimits
+	var cpu time.Duration
+	for _, l := range tests {
+		suite.Time += l.timeout()
+		cpu += l.apply(defaults).CPUTime
+	}
+	suite.MemoryMB = question.MemoryMB
+	limits := suite.apply(defaults)
+	limits.CPUTime = cpu
+	return limits
+}
+
+// recordUsage stores the time and memory the run of the submission took with the result it
+// graded. Results not graded by a run of their own, such as those of a test suite, keep 0.
+func recordUsage(result *model.TestcaseResult, run executer.ExecutionResult) {
+	result.WallTimeMs = int(run.WallTime / time.Millisecond)
+	result.CPUTimeMs = int(run.CPUTime / time.Millisecond)
+	result.PeakMemoryKB = int(run.PeakRSS >> 10)
+}
+
+// record stores l with the result it graded
+func (l testLimits) record(result *model.TestcaseResult) {
+	result.TimeLimitMs = int(l.timeout() / time.Millisecond)
+	result.MemoryLimitMB = l.MemoryMB
+}
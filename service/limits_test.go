@@ -0,0 +1,51 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+// TestResolveLimits checks that a limit set for the run beats the testcase's, which beats the
+// question's, which beats the configured default, each for time and memory on its own
+func TestResolveLimits(t *testing.T) {
+	defaults := executer.Limits{CPUTime: 5 * time.Second, AddressSpace: 256 << 20}
+	cases := []struct {
+		name     string
+		question model.Question
+		tc       model.Testcase
+		opts     Options
+		want     testLimits
+	}{
+		{"defaults", model.Question{}, model.Testcase{}, Options{}, testLimits{defaultTimeLimit, 256}},
+		{"question", model.Question{TimeLimitMs: 2000, MemoryLimitMB: 64}, model.Testcase{}, Options{}, testLimits{2 * time.Second, 64}},
+		{"testcase", model.Question{TimeLimitMs: 2000, MemoryLimitMB: 64}, model.Testcase{TimeLimitMs: 500}, Options{}, testLimits{500 * time.Millisecond, 64}},
+		{"options", model.Question{TimeLimitMs: 2000}, model.Testcase{MemoryLimitMB: 32}, Options{TimeLimit: 3 * time.Second, MemoryLimitMB: 128}, testLimits{3 * time.Second, 128}},
+	}
+	for _, c := range cases {
+		if got := resolveLimits(defaults, c.question, c.tc, c.opts); got != c.want {
+			t.Errorf("%s: got %+v, want %+v", c.name, got, c.want)
+		}
+	}
+
+	applied := testLimits{Time: 2 * time.Second, MemoryMB: 64}.apply(defaults)
+	if applied.CPUTime != 2*time.Second || applied.WallTime != 2*time.Second || applied.AddressSpace != 64<<20 {
+		t.Errorf("the limits were not applied to the executor: %+v", applied)
+	}
+	if applied := (testLimits{Time: time.Minute}).apply(defaults); applied.CPUTime != defaults.CPUTime || applied.AddressSpace != 0 {
+		t.Errorf("a longer time limit should keep the configured CPU time: %+v", applied)
+	}
+
+	suite := suiteLimits(defaults, testLimits{Time: time.Second, MemoryMB: 64}, []testLimits{{Time: 2 * time.Second}, {Time: time.Minute}, {}})
+	if suite.CPUTime != 2*time.Second+defaults.CPUTime+defaults.CPUTime || suite.AddressSpace != 64<<20 {
+		t.Errorf("a test suite should get the CPU time of all its tests: %+v", suite)
+	}
+
+	var result model.TestcaseResult
+	testLimits{Time: 1500 * time.Millisecond, MemoryMB: 64}.record(&result)
+	if result.TimeLimitMs != 1500 || result.MemoryLimitMB != 64 {
+		t.Errorf("unexpected recorded limits %+v", result)
+	}
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Default tolerances of the numeric comparator, overridable with the abs and rel options
+const (
+	defaultAbsTolerance = 1e-5
+	defaultRelTolerance = 1e-5
+)
+
+// numberPattern finds decimal numbers inside a whitespace-separated field, so "avg=3.5," yields
+// the tokens "avg=", "3.5" and ",".
+var numberPattern = regexp.MustCompile(`[-+]?(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?`)
+
+// NumericComparator compares outputs line by line, accepting numeric tokens that are within
+// AbsTolerance or within RelTolerance of the expected magnitude; other tokens must match exactly.
+type NumericComparator struct {
+	AbsTolerance float64
+	RelTolerance float64
+}
+
+// newNumericComparator builds a NumericComparator from the "abs" and "rel" options,
+// e.g. "numeric:abs=1e-3,rel=0".
+func newNumericComparator(options map[string]string) (Comparator, error) {
+	c := &NumericComparator{AbsTolerance: defaultAbsTolerance, RelTolerance: defaultRelTolerance}
+	for key, value := range options {
+		tolerance, err := strconv.ParseFloat(value, 64)
+		if err != nil || tolerance < 0 || math.IsNaN(tolerance) {
+			return nil, fmt.Errorf("numeric option %s must be a non-negative number, got %q", key, value)
+		}
+		switch key {
+		case "abs":
+			c.AbsTolerance = tolerance
+		case "rel":
+			c.RelTolerance = tolerance
+		default:
+			return nil, fmt.Errorf("unknown numeric option %q", key)
+		}
+	}
+	return c, nil
+}
+
+// Compare scores the fraction of expected lines whose tokens all matched and reports the
+// first mismatching token.
+func (c *NumericComparator) Compare(got string, want string) Comparison {
+	gotLines := splitLines(got)
+	wantLines := splitLines(want)
+
+	reason := ""
+	comparison := compareSequence("line", gotLines, wantLines, func(i int) bool {
+		mismatch := c.compareLine(gotLines[i], wantLines[i])
+		if mismatch != "" && reason == "" {
+			reason = fmt.Sprintf("line %d %s", i+1, mismatch)
+		}
+		return mismatch == ""
+	})
+	if reason != "" {
+		comparison.Reason = reason
+	}
+	return comparison
+}
+
+// compareLine returns a description of the first mismatching token, or "" when the lines match
+func (c *NumericComparator) compareLine(got string, want string) string {
+	gotTokens := numericTokens(got)
+	wantTokens := numericTokens(want)
+
+	for i, w := range wantTokens {
+		if i >= len(gotTokens) {
+			return fmt.Sprintf("token %d missing, expected %q", i+1, w)
+		}
+		g := gotTokens[i]
+		gNum, gErr := strconv.ParseFloat(g, 64)
+		wNum, wErr := strconv.ParseFloat(w, 64)
+		if gErr != nil || wErr != nil {
+			if g != w {
+				return fmt.Sprintf("token %d: got %q, expected %q", i+1, g, w)
+			}
+			continue
+		}
+		if !c.withinTolerance(gNum, wNum) {
+			return fmt.Sprintf("token %d: got %s, expected %s (difference %g exceeds abs %g / rel %g)",
+				i+1, g, w, math.Abs(gNum-wNum), c.AbsTolerance, c.RelTolerance)
+		}
+	}
+	if len(gotTokens) > len(wantTokens) {
+		return fmt.Sprintf("token %d unexpected %q", len(wantTokens)+1, gotTokens[len(wantTokens)])
+	}
+	return ""
+}
+
+func (c *NumericComparator) withinTolerance(got float64, want float64) bool {
+	if got == want {
+		return true
+	}
+	diff := math.Abs(got - want)
+	if diff <= c.AbsTolerance {
+		return true
+	}
+	return diff <= c.RelTolerance*math.Max(math.Abs(got), math.Abs(want))
+}
+
+// numericTokens splits a line on whitespace and then separates numbers from surrounding text
+func numericTokens(line string) []string {
+	var tokens []string
+	for _, field := range strings.Fields(line) {
+		last := 0
+		for _, loc := range numberPattern.FindAllStringIndex(field, -1) {
+			if loc[0] > last {
+				tokens = append(tokens, field[last:loc[0]])
+			}
+			tokens = append(tokens, field[loc[0]:loc[1]])
+			last = loc[1]
+		}
+		if last < len(field) {
+			tokens = append(tokens, field[last:])
+		}
+	}
+	return tokens
+}
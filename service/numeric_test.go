@@ -0,0 +1,48 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestNumericComparator covers tolerances, mixed text/number tokens and mismatch reporting
+func TestNumericComparator(t *testing.T) {
+	cases := []struct {
+		spec       string
+		got        string
+		want       string
+		wantMatch  bool
+		wantSim    float32
+		wantReason string
+	}{
+		{"numeric", "3.3333333", "3.33333", true, 1, ""},
+		{"numeric", "avg=3.3333333, n=3", "avg=3.33333, n=3", true, 1, ""},
+		{"numeric", "1e6\n2", "1000000.0\n2.0", true, 1, ""},
+		{"numeric", "avg=3.4", "avg=3.33333", false, 0, `line 1 token 2: got 3.4, expected 3.33333`},
+		{"numeric", "mean 1\nmax 9", "mean 1\nmin 9", false, 0.5, `line 2 token 1: got "max", expected "min"`},
+		{"numeric", "1 2", "1 2 3", false, 0, "token 3 missing"},
+		{"numeric:abs=0.1,rel=0", "10.05", "10", true, 1, ""},
+		{"numeric:abs=0,rel=0.01", "1000.5", "1000", true, 1, ""},
+		{"numeric:abs=0,rel=0", "1.0000001", "1", false, 0, "exceeds abs 0 / rel 0"},
+	}
+
+	for _, c := range cases {
+		comparator, err := NewComparator(c.spec)
+		if err != nil {
+			t.Fatalf("NewComparator(%q): %v", c.spec, err)
+		}
+		result := comparator.Compare(c.got, c.want)
+		if result.Match != c.wantMatch || result.Similarity != c.wantSim {
+			t.Errorf("%s: Compare(%q, %q) = %v, %v; want %v, %v", c.spec, c.got, c.want, result.Match, result.Similarity, c.wantMatch, c.wantSim)
+		}
+		if !strings.Contains(result.Reason, c.wantReason) || (c.wantReason == "" && result.Reason != "") {
+			t.Errorf("%s: reason %q does not contain %q", c.spec, result.Reason, c.wantReason)
+		}
+	}
+
+	for _, spec := range []string{"numeric:abs=-1", "numeric:rel=x", "numeric:eps=1"} {
+		if _, err := NewComparator(spec); err == nil {
+			t.Errorf("NewComparator(%q) succeeded, want error", spec)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+// Options adjust a grading run, typically from command line flags.
+// The zero value grades with each question's own settings.
+type Options struct {
+	// Interpreter names the Python interpreter to use, overriding the question's choice
+	Interpreter string
+	// WarmWorkers is the number of warm workers Python submissions run on, see
+	// executer.WorkerPool; 0 starts the interpreter for every run
+	WarmWorkers int
+	// ParallelTestcases is how many testcases of a submission run at once; 0 or 1 runs
+	// them one after another
+	ParallelTestcases int
+	// TimeLimit and MemoryLimitMB override the limits of every testcase when set
+	TimeLimit     time.Duration
+	MemoryLimitMB int
+	// EfficiencyMultiple overrides the efficiency multiple of every question when set
+	EfficiencyMultiple float64
+
+	// slots caps the programs running at once across every submission graded with these
+	// options; GradeSubmission makes its own when it is nil
+	slots runSlots
+}
+
+var (
+	warmPoolsMu sync.Mutex
+	warmPools   = map[string]*executer.WorkerPool{}
+)
+
+// warmPool returns the pool of warm workers for interpreter, shared by every submission
+// this process grades so the workers outlive a single submission
+func warmPool(interpreter string, limits executer.Limits, size int) *executer.WorkerPool {
+	warmPoolsMu.Lock()
+	defer warmPoolsMu.Unlock()
+	pool, ok := warmPools[interpreter]
+	if !ok {
+		pool = executer.NewWorkerPool(interpreter, limits, size)
+		warmPools[interpreter] = pool
+	}
+	return pool
+}
+
+// newRunner creates the executor for the question's language that runs under limits. For
+// Python, the interpreter comes from the options, then the question, then the configured default.
+func newRunner(question model.Question, opts Options, limits executer.Limits) (executer.Executor, error) {
+	runner, err := executer.NewExecutor(question.Language, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	if python, ok := runner.(*executer.PythonExecutor); ok {
+		name := opts.Interpreter
+		if name == "" {
+			name = question.Interpreter
+		}
+		interpreter, err := executer.LookupInterpreter(name)
+		if err != nil {
+			return nil, err
+		}
+		python.Interpreter = interpreter.Path
+		if opts.WarmWorkers > 0 {
+			python.Pool = warmPool(python.Interpreter, python.Limits, opts.WarmWorkers)
+		}
+	}
+	return runner, nil
+}
+
+// newRunners creates an executor of program under each of limits, applied to defaults
+func newRunners(program model.Question, opts Options, defaults executer.Limits, limits []testLimits) (map[testLimits]executer.Executor, error) {
+	runners := map[testLimits]executer.Executor{}
+	for _, l := range limits {
+		if _, ok := runners[l]; ok {
+			continue
+		}
+		runner, err := newRunner(program, opts, l.apply(defaults))
+		if err != nil {
+			return nil, err
+		}
+		runners[l] = runner
+	}
+	return runners, nil
+}
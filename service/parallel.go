@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+// runSlots caps how many programs run at once across all the submissions being graded, so
+// parallel testcases and concurrent submissions together do not oversubscribe the host.
+// A program runs only while it holds a slot.
+type runSlots chan struct{}
+
+func newRunSlots(n int) runSlots {
+	if n < 1 {
+		n = 1
+	}
+	return make(runSlots, n)
+}
+
+// acquire waits for a free slot, returning false when ctx ends first
+func (s runSlots) acquire(ctx context.Context) bool {
+	select {
+	case s <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (s runSlots) release() {
+	<-s
+}
+
+// grading is what every testcase of one submission is judged with
+type grading struct {
+	sourceCodeId int
+	runner       executer.Executor
+	checker      *Checker
+	interactor   *Interactor
+	efficiency   *Efficiency
+	question     model.Question
+	submission   Submission
+	static       staticOutcome
+	// limits are those of each testcase by its ID, and runners the executors for each of
+	// them; a testcase without either runs on runner with the default time limit
+	limits  map[int]testLimits
+	runners map[testLimits]executer.Executor
+	// suite is the run of the question's test suite, nil when no testcase is graded by it
+	suite *suiteRun
+}
+
+// gradeTestcases judges the testcases, up to parallel of them at once, and returns their
+// results in the order of testcases. Each testcase is judged within its testTime, counted
+// from when it holds a slot; one that runs out of it is an internal error. When ctx ends
+// before every testcase was judged, those that were not, or whose run it cut short, are
+// internal errors too and the error of ctx is returned with the results.
+func (g *grading) gradeTestcases(ctx context.Context, testcases []model.Testcase, parallel int, slots runSlots) ([]model.TestcaseResult, error) {
+	if parallel < 1 {
+		parallel = 1
+	}
+	results := make([]model.TestcaseResult, len(testcases))
+	judged := make([]bool, len(testcases))
+	running := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, tc := range testcases {
+		wg.Add(1)
+		go func(i int, tc model.Testcase) {
+			defer wg.Done()
+			select {
+			case running <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-running }()
+			if !slots.acquire(ctx) {
+				return
+			}
+			defer slots.release()
+			if ctx.Err() != nil {
+				return
+			}
+			// The testcase's time is counted only once it holds a slot, so waiting for one
+			// never runs it out of time
+			testCtx, testCancel := context.WithTimeout(ctx, g.testTime(tc))
+			defer testCancel()
+			result := g.gradeTestcase(testCtx, tc)
+			// A run stopped by a deadline rather than by its own limits has not been judged
+			if ctx.Err() != nil {
+				return
+			}
+			if testCtx.Err() != nil {
+				result = g.ungradedResult(tc, testCtx.Err())
+			}
+			results[i], judged[i] = result, true
+		}(i, tc)
+	}
+	wg.Wait()
+
+	var err error
+	for i, tc := range testcases {
+		if !judged[i] {
+			results[i] = g.ungradedResult(tc, ctx.Err())
+			err = ctx.Err()
+		}
+	}
+	return results, err
+}
+
+// testTime is the time judging tc may take: a run of the submission, and one more each for
+// the checker and a reference solution not timed yet that may run after it
+func (g *grading) testTime(tc model.Testcase) time.Duration {
+	_, limits := g.runnerFor(tc)
+	runs := 1
+	if g.checker != nil {
+		runs++
+	}
+	if g.efficiency != nil {
+		runs++
+	}
+	return time.Duration(runs)*limits.timeout() + testcaseMargin
+}
+
+// ungradedResults is ungradedResult for every testcase
+func (g *grading) ungradedResults(testcases []model.Testcase, err error) []model.TestcaseResult {
+	results := make([]model.TestcaseResult, len(testcases))
+	for i, tc := range testcases {
+		results[i] = g.ungradedResult(tc, err)
+	}
+	return results
+}
+
+// ungradedResult is the result of a testcase the grading stopped with err before judging
+func (g *grading) ungradedResult(tc model.Testcase, err error) model.TestcaseResult {
+	result := model.TestcaseResult{
+		StudentQuestionFileV2Id: g.sourceCodeId,
+		TestcaseId:              tc.TestcaseId,
+		Verdict:                 model.VerdictInternalError,
+		TestOutputText:          judgeNote("", fmt.Sprintf("grading stopped before the testcase was judged: %v", err)),
+	}
+	result.Status = result.Verdict.Status()
+	_, limits := g.runnerFor(tc)
+	limits.record(&result)
+	return result
+}
+
+// runnerFor returns the executor a testcase runs on and its limits
+func (g *grading) runnerFor(tc model.Testcase) (executer.Executor, testLimits) {
+	limits := g.limits[tc.TestcaseId]
+	if runner, ok := g.runners[limits]; ok {
+		return runner, limits
+	}
+	return g.runner, limits
+}
+
+// gradeTestcase judges one testcase and returns the result to record
+func (g *grading) gradeTestcase(ctx context.Context, tc model.Testcase) model.TestcaseResult {
+	testResult := model.TestcaseResult{
+		StudentQuestionFileV2Id: g.sourceCodeId,
+		TestcaseId:              tc.TestcaseId,
+		Score:                   int(tc.Score),
+		Status:                  "N",
+		TestOutputText:          "",
+	}
+	runner, limits := g.runnerFor(tc)
+	limits.record(&testResult)
+
+	var similarity float32 = 0
+	var run executer.ExecutionResult
+	if g.static.verdict != "" {
+		testResult.Verdict, testResult.TestOutputText = g.static.verdict, judgeNote("", g.static.note)
+	} else if tc.TestName != "" {
+		testResult.Verdict, testResult.TestOutputText = g.suite.judge(tc)
+	} else if g.interactor != nil && tc.FunctionName == "" {
+		testResult.Verdict, testResult.TestOutputText, similarity, run = g.interactor.judge(ctx, runner, g.submission, tc, limits)
+	} else {
+		testResult.Verdict, testResult.TestOutputText, similarity, run = runTestcase(ctx, runner, g.checker, g.question, g.submission, tc, limits)
+		if g.efficiency != nil && testResult.Verdict == model.VerdictAccepted {
+			// A slow accepted run loses part of the score without changing the verdict
+			factor, note := g.efficiency.judge(ctx, runner, tc, run)
+			testResult.Score = int(float64(testResult.Score) * factor)
+			if note != "" {
+				testResult.TestOutputText = judgeNote(testResult.TestOutputText, note)
+			}
+		}
+	}
+	recordUsage(&testResult, run)
+	testResult.Status = testResult.Verdict.Status()
+	if testResult.Verdict != model.VerdictAccepted {
+		testResult.Score = int(float32(testResult.Score) * similarity)
+	}
+	if g.static.verdict == "" && g.static.note != "" {
+		// Broken soft rules cost part of the score without changing the verdict
+		testResult.Score = int(float64(testResult.Score) * g.static.factor)
+		testResult.TestOutputText = judgeNote(testResult.TestOutputText, g.static.note)
+	}
+	return testResult
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+// echoExecutor echoes stdin after a short pause, recording how many runs overlapped
+type echoExecutor struct {
+	mu      sync.Mutex
+	running int
+	most    int
+}
+
+func (e *echoExecutor) Execute(ctx context.Context, code string, stdin string) (executer.ExecutionResult, error) {
+	return e.Run(ctx, executer.Input{Code: code, Stdin: stdin})
+}
+
+func (e *echoExecutor) Run(ctx context.Context, input executer.Input) (executer.ExecutionResult, error) {
+	e.mu.Lock()
+	e.running++
+	e.most = max(e.most, e.running)
+	e.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	e.mu.Lock()
+	e.running--
+	e.mu.Unlock()
+	return executer.ExecutionResult{Stdout: input.Stdin, WallTime: 20 * time.Millisecond, CPUTime: 5 * time.Millisecond, PeakRSS: 8 << 20}, nil
+}
+
+func (e *echoExecutor) Version() (string, error) { return "echo", nil }
+
+// stuckExecutor runs until ctx ends, as a program without a time limit of its own would
+type stuckExecutor struct{ echoExecutor }
+
+func (e *stuckExecutor) Run(ctx context.Context, input executer.Input) (executer.ExecutionResult, error) {
+	<-ctx.Done()
+	return executer.ExecutionResult{ExitCode: -1, Signal: "SIGKILL", TimedOut: true, Limit: executer.LimitTime}, nil
+}
+
+func (e *echoExecutor) Name() string { return "echo" }
+
+// TestGradeTestcases checks that testcases run no more at once than both the submission and
+// the shared slots allow, that their results keep the order of the testcases and what each
+// run took, and that a cancelled grading still gives every testcase a result
+func TestGradeTestcases(t *testing.T) {
+	var testcases []model.Testcase
+	for i := 1; i <= 12; i++ {
+		// Every third testcase fails, so results out of order would show
+		output := strconv.Itoa(i)
+		if i%3 == 0 {
+			output = "wrong"
+		}
+		testcases = append(testcases, model.Testcase{TestcaseId: i, Score: 1, TestcaseInput: strconv.Itoa(i), TestcaseOutput: output})
+	}
+
+	for _, c := range []struct{ parallel, slots, most int }{{0, 4, 1}, {3, 8, 3}, {8, 2, 2}} {
+		runner := &echoExecutor{}
+		g := &grading{sourceCodeId: 7, runner: runner}
+		results, err := g.gradeTestcases(context.Background(), testcases, c.parallel, newRunSlots(c.slots))
+		if err != nil {
+			t.Fatalf("parallel %d: %v", c.parallel, err)
+		}
+		if runner.most != c.most {
+			t.Errorf("parallel %d with %d slots: %d testcases ran at once, expected %d", c.parallel, c.slots, runner.most, c.most)
+		}
+		if len(results) != len(testcases) {
+			t.Fatalf("expected a result for each testcase, got %d", len(results))
+		}
+		for i, r := range results {
+			want := model.VerdictAccepted
+			if (i+1)%3 == 0 {
+				want = model.VerdictWrongAnswer
+			}
+			if r.TestcaseId != i+1 || r.StudentQuestionFileV2Id != 7 || r.Verdict != want {
+				t.Fatalf("result %d out of order: %+v", i, r)
+			}
+			if r.WallTimeMs != 20 || r.CPUTimeMs != 5 || r.PeakMemoryKB != 8<<10 {
+				t.Fatalf("result %d does not record its run: %+v", i, r)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err := (&grading{runner: &echoExecutor{}}).gradeTestcases(ctx, testcases, 4, newRunSlots(4))
+	if err == nil || len(results) != len(testcases) {
+		t.Fatalf("expected a cancelled grading to report a result for each testcase, got %d results (%v)", len(results), err)
+	}
+	for i, r := range results {
+		if r.TestcaseId != i+1 || r.Verdict != model.VerdictInternalError || r.Status != model.VerdictInternalError.Status() || r.Score != 0 ||
+			!strings.Contains(r.TestOutputText, "grading stopped before the testcase was judged") {
+			t.Fatalf("unexpected result %d of a cancelled grading: %+v", i, r)
+		}
+	}
+
+	// A run cut short by the end of the grading did not exceed the testcase's time limit
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	results, err = (&grading{runner: &stuckExecutor{}}).gradeTestcases(ctx, testcases[:1], 1, newRunSlots(1))
+	if err == nil || results[0].Verdict != model.VerdictInternalError {
+		t.Fatalf("expected a run stopped by the grading to be an internal error, got %+v (%v)", results[0], err)
+	}
+}
+
+// deadlineExecutor echoes stdin like echoExecutor, recording the time each run had left
+type deadlineExecutor struct {
+	echoExecutor
+	left []time.Duration
+}
+
+func (e *deadlineExecutor) Run(ctx context.Context, input executer.Input) (executer.ExecutionResult, error) {
+	deadline, _ := ctx.Deadline()
+	e.mu.Lock()
+	e.left = append(e.left, time.Until(deadline))
+	e.mu.Unlock()
+	return e.echoExecutor.Run(ctx, input)
+}
+
+// TestGradeTestcases_Queued checks that testcases waiting for a slot do not lose any of
+// their time while they wait
+func TestGradeTestcases_Queued(t *testing.T) {
+	var testcases []model.Testcase
+	limits := map[int]testLimits{}
+	for i := 1; i <= 8; i++ {
+		testcases = append(testcases, model.Testcase{TestcaseId: i, Score: 1, TestcaseInput: "x", TestcaseOutput: "x"})
+		limits[i] = testLimits{Time: 30 * time.Millisecond}
+	}
+	runner := &deadlineExecutor{}
+	g := &grading{runner: runner, limits: limits}
+	results, err := g.gradeTestcases(context.Background(), testcases, len(testcases), newRunSlots(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range results {
+		if r.Verdict != model.VerdictAccepted {
+			t.Fatalf("result %d was not judged: %+v", i, r)
+		}
+	}
+	// The last testcases wait for three runs before them, more than a run's own time
+	want := g.testTime(testcases[0]) - 10*time.Millisecond
+	for i, left := range runner.left {
+		if left < want {
+			t.Errorf("run %d started with %v left, expected its whole %v", i, left, g.testTime(testcases[0]))
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RegexMatch prefixes selecting how a testcase pattern is anchored.
+// A pattern without a prefix is matched against the whole output.
+const (
+	regexFullPrefix = "full:"
+	regexLinePrefix = "line:"
+)
+
+// compareRegex judges the program output against a testcase's RegexMatch pattern.
+// It returns whether the output passed, the similarity ratio used for partial score
+// and, when it did not pass, the reason to record with the test result.
+func compareRegex(got string, pattern string) (bool, float32, string) {
+	got = strings.ReplaceAll(got, "\r\n", "\n")
+	pattern = strings.ReplaceAll(pattern, "\r\n", "\n")
+
+	switch {
+	case strings.HasPrefix(pattern, regexLinePrefix):
+		return compareRegexLines(got, strings.TrimPrefix(pattern, regexLinePrefix))
+	case strings.HasPrefix(pattern, regexFullPrefix):
+		return compareRegexWhole(got, strings.TrimPrefix(pattern, regexFullPrefix))
+	default:
+		return compareRegexWhole(got, pattern)
+	}
+}
+
+// compareRegexWhole requires the pattern to match the entire trimmed output.
+// The pattern runs in (?s) mode so that "." also spans line breaks.
+func compareRegexWhole(got string, pattern string) (bool, float32, string) {
+	pattern = strings.TrimSpace(pattern)
+	re, err := regexp.Compile(`(?s)\A(?:` + pattern + `)\z`)
+	if err != nil {
+		return false, 0, fmt.Sprintf("regex: invalid pattern %q: %v", pattern, err)
+	}
+	if !re.MatchString(strings.TrimSpace(got)) {
+		return false, 0, fmt.Sprintf("regex: output does not match /%s/", pattern)
+	}
+	return true, 1, ""
+}
+
+// compareRegexLines splits the pattern on newlines and requires each pattern line
+// to match the output line at the same position in full. Like compareResult, the
+// similarity is the fraction of pattern lines that matched.
+func compareRegexLines(got string, pattern string) (bool, float32, string) {
+	gotLines := strings.Split(strings.TrimSpace(got), "\n")
+	patternLines := strings.Split(strings.TrimSpace(pattern), "\n")
+
+	var matched float32 = 0
+	reason := ""
+	for i, p := range patternLines {
+		p = strings.TrimSpace(p)
+		re, err := regexp.Compile(`\A(?:` + p + `)\z`)
+		if err != nil {
+			return false, 0, fmt.Sprintf("regex: invalid pattern on line %d %q: %v", i+1, p, err)
+		}
+		if i >= len(gotLines) {
+			if reason == "" {
+				reason = fmt.Sprintf("regex: line %d missing, expected /%s/", i+1, p)
+			}
+			continue
+		}
+		line := strings.TrimSpace(gotLines[i])
+		if re.MatchString(line) {
+			matched += 1.0
+		} else if reason == "" {
+			reason = fmt.Sprintf("regex: line %d %q does not match /%s/", i+1, line, p)
+		}
+	}
+
+	similarity := matched / float32(len(patternLines))
+	return similarity == 1.0, similarity, reason
+}
+
+// appendJudgeNote attaches the judge's reason for a verdict to the stored output.
+func appendJudgeNote(output string, note string) string {
+	if note == "" {
+		return output
+	}
+	return strings.TrimRight(output, "\n") + "\n\n[judge] " + note
+}
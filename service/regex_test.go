@@ -0,0 +1,39 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestCompareRegex covers whole-output and per-line anchoring of RegexMatch patterns
+func TestCompareRegex(t *testing.T) {
+	cases := []struct {
+		name       string
+		got        string
+		pattern    string
+		wantMatch  bool
+		wantSim    float32
+		wantReason string
+	}{
+		{"whole float", "result: 3.14159\n", `result: 3\.14\d*`, true, 1, ""},
+		{"whole full prefix", "a\nb\n", `full:a\nb`, true, 1, ""},
+		{"whole dot spans lines", "start\n12:00:01\nend", `start.*end`, true, 1, ""},
+		{"whole is anchored", "xx 42 yy", `\d+`, false, 0, "does not match"},
+		{"lines all match", "t=1.5\r\nt=2.25\r\n", "line:t=\\d+\\.\\d+\nt=\\d+\\.\\d+", true, 1, ""},
+		{"lines partial", "ok\nbad", "line:ok\n\\d+", false, 0.5, "line 2"},
+		{"lines missing", "ok", "line:ok\nok", false, 0.5, "line 2 missing"},
+		{"invalid pattern", "x", `(`, false, 0, "invalid pattern"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			match, sim, reason := compareRegex(c.got, c.pattern)
+			if match != c.wantMatch || sim != c.wantSim {
+				t.Fatalf("compareRegex(%q, %q) = %v, %v; want %v, %v", c.got, c.pattern, match, sim, c.wantMatch, c.wantSim)
+			}
+			if !strings.Contains(reason, c.wantReason) || (c.wantReason == "" && reason != "") {
+				t.Fatalf("reason %q does not contain %q", reason, c.wantReason)
+			}
+		})
+	}
+}
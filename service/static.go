@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+// staticAnalyzer parses submissions with the question's interpreter, see static_analyzer.py
+//
+//go:embed static_analyzer.py
+var staticAnalyzer string
+
+// staticRulesFile is where the analyzer finds the rules and the files to check
+const staticRulesFile = "_static_rules.json"
+
+// Kinds of static rules, as named in StaticRules.Soft and in violations
+const (
+	ruleBannedModules = "banned_modules"
+	ruleBannedCalls   = "banned_calls"
+	ruleRequired      = "required"
+	ruleMaxLines      = "max_lines"
+)
+
+// staticConstructs are the constructs StaticRules.Required may name
+var staticConstructs = []string{
+	"class", "comprehension", "for", "function", "lambda", "loop", "recursion", "try", "while", "with", "yield",
+}
+
+// StaticRules restrict how the submission of a question may be written. They are checked
+// on its source, before any testcase runs, and only for Python. A broken rule is hard and
+// stops grading with a Rule Violation verdict unless its kind is listed in Soft, in which
+// case every testcase loses Penalty of its score for each soft kind that was broken.
+type StaticRules struct {
+	// BannedModules may not be imported, nor any of their submodules
+	BannedModules []string `json:"banned_modules,omitempty"`
+	// BannedCalls are functions that may not be used, such as "eval" or "os.system"
+	BannedCalls []string `json:"banned_calls,omitempty"`
+	// Required are constructs the submission must use, see staticConstructs
+	Required []string `json:"required,omitempty"`
+	// MaxLines bounds the lines of code, not counting blank and comment lines; 0 for no bound
+	MaxLines int `json:"max_lines,omitempty"`
+
+	Soft    []string `json:"soft,omitempty"`
+	Penalty float64  `json:"penalty,omitempty"`
+}
+
+// ParseStaticRules reads the static rules of a question, returning nil when it has none
+func ParseStaticRules(raw string) (*StaticRules, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var rules StaticRules
+	if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+		return nil, fmt.Errorf("invalid static rules: %v", err)
+	}
+	for _, construct := range rules.Required {
+		i := sort.SearchStrings(staticConstructs, construct)
+		if i == len(staticConstructs) || staticConstructs[i] != construct {
+			return nil, fmt.Errorf("invalid static rules: unknown construct %q", construct)
+		}
+	}
+	for _, kind := range rules.Soft {
+		switch kind {
+		case ruleBannedModules, ruleBannedCalls, ruleRequired, ruleMaxLines:
+		default:
+			return nil, fmt.Errorf("invalid static rules: unknown rule %q", kind)
+		}
+	}
+	if rules.MaxLines < 0 || rules.Penalty < 0 || rules.Penalty > 1 {
+		return nil, fmt.Errorf("invalid static rules: max_lines must not be negative and penalty must be between 0 and 1")
+	}
+	return &rules, nil
+}
+
+// staticViolation is a broken rule as the analyzer reports it
+type staticViolation struct {
+	Rule    string `json:"rule"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// staticOutcome is how the static checks of a submission affect its testcases
+type staticOutcome struct {
+	// verdict is set when the testcases are not run, with note explaining why
+	verdict model.Verdict
+	// note lists the violations, recorded with every testcase
+	note string
+	// factor is the fraction of each testcase score that is kept
+	factor float64
+}
+
+// checkStaticRules analyzes the submission against the question's static rules. A
+// submission that does not parse is left to the testcases to report as a compile error.
+// The analysis gets the time of a run under limits.
+func checkStaticRules(ctx context.Context, runner executer.Executor, question model.Question, submission Submission, limits testLimits) staticOutcome {
+	rules, err := ParseStaticRules(question.StaticRules)
+	if err != nil {
+		return staticOutcome{verdict: model.VerdictInternalError, note: err.Error()}
+	}
+	if rules == nil {
+		return staticOutcome{factor: 1}
+	}
+	if runner.Name() != "python" {
+		return staticOutcome{verdict: model.VerdictInternalError, note: fmt.Sprintf("static rules are only supported for python, not %s", runner.Name())}
+	}
+
+	violations, err := analyzeSubmission(ctx, runner, rules, submission, limits)
+	if err != nil {
+		return staticOutcome{verdict: model.VerdictInternalError, note: err.Error()}
+	}
+	return rules.outcome(violations)
+}
+
+// analyzeSubmission runs the analyzer on the submission and returns the rules it broke
+func analyzeSubmission(ctx context.Context, runner executer.Executor, rules *StaticRules, submission Submission, limits testLimits) ([]staticViolation, error) {
+	modules, err := harnessModules(submission)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{functionModule + ".py"}
+	for name := range submission.Modules {
+		if strings.HasSuffix(name, ".py") {
+			files = append(files, name)
+		}
+	}
+	sort.Strings(files[1:])
+	config, err := json.Marshal(struct {
+		*StaticRules
+		Files []string `json:"files"`
+		Path  string   `json:"path"`
+	}{rules, files, sourceDir})
+	if err != nil {
+		return nil, err
+	}
+
+	analyzeCtx, cancel := context.WithTimeout(ctx, limits.timeout())
+	defer cancel()
+	result, err := runner.Run(analyzeCtx, executer.Input{
+		Code:    staticAnalyzer,
+		Modules: modules,
+		Files:   map[string]string{staticRulesFile: string(config)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("static analysis could not be run: %v", err)
+	}
+	if !result.Succeeded() {
+		return nil, fmt.Errorf("static analysis failed: %v", result.Err())
+	}
+	var report struct {
+		SyntaxError bool              `json:"syntax_error"`
+		Violations  []staticViolation `json:"violations"`
+	}
+	if err := json.Unmarshal([]byte(result.Stdout), &report); err != nil {
+		return nil, fmt.Errorf("static analysis report could not be read: %v", err)
+	}
+	return report.Violations, nil
+}
+
+// outcome decides what the violations mean for the testcases
+func (r *StaticRules) outcome(violations []staticViolation) staticOutcome {
+	if len(violations) == 0 {
+		return staticOutcome{factor: 1}
+	}
+	soft := map[string]bool{}
+	for _, kind := range r.Soft {
+		soft[kind] = true
+	}
+
+	var messages []string
+	hard := false
+	brokenSoft := map[string]bool{}
+	for _, v := range violations {
+		messages = append(messages, v.Message)
+		if soft[v.Rule] {
+			brokenSoft[v.Rule] = true
+		} else {
+			hard = true
+		}
+	}
+	note := "static rules: " + strings.Join(messages, "; ")
+	if hard {
+		return staticOutcome{verdict: model.VerdictRuleViolation, note: note}
+	}
+	factor := 1 - r.Penalty*float64(len(brokenSoft))
+	if factor < 0 {
+		factor = 0
+	}
+	return staticOutcome{note: note, factor: factor}
+}
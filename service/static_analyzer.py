@@ -0,0 +1,138 @@
+# Static analysis of a submission, run with the question's interpreter. It reads the rules
+# from RULES_FILE, parses each source file with ast without running it and prints
+# {"syntax_error": bool, "violations": [{"rule": ..., "line": ..., "message": ...}]}.
+# Rules are named like the fields of service.StaticRules. The source files are read from the
+# directory the rules name, which is never put on the import path.
+import ast
+import json
+import os
+
+RULES_FILE = "_static_rules.json"
+
+LOOPS = (ast.For, ast.While, getattr(ast, "AsyncFor", ast.For))
+COMPREHENSIONS = (ast.ListComp, ast.SetComp, ast.DictComp, ast.GeneratorExp)
+FUNCTIONS = (ast.FunctionDef, getattr(ast, "AsyncFunctionDef", ast.FunctionDef))
+
+# Constructs a rule set may require, by name
+CONSTRUCTS = {
+    "loop": lambda node: isinstance(node, LOOPS),
+    "for": lambda node: isinstance(node, ast.For),
+    "while": lambda node: isinstance(node, ast.While),
+    "comprehension": lambda node: isinstance(node, COMPREHENSIONS),
+    "function": lambda node: isinstance(node, FUNCTIONS),
+    "class": lambda node: isinstance(node, ast.ClassDef),
+    "lambda": lambda node: isinstance(node, ast.Lambda),
+    "try": lambda node: isinstance(node, ast.Try),
+    "with": lambda node: isinstance(node, ast.With),
+    "yield": lambda node: isinstance(node, (ast.Yield, ast.YieldFrom)),
+}
+
+
+def dotted_name(node):
+    # "a.b.c" for a chain of attributes on a name, None for anything else
+    parts = []
+    while isinstance(node, ast.Attribute):
+        parts.append(node.attr)
+        node = node.value
+    if not isinstance(node, ast.Name):
+        return None
+    parts.append(node.id)
+    return ".".join(reversed(parts))
+
+
+def matches(name, rule):
+    return name == rule or name.endswith("." + rule)
+
+
+def banned_module(module, banned):
+    for rule in banned:
+        if module == rule or module.startswith(rule + "."):
+            return rule
+    return None
+
+
+def is_recursive(function):
+    for node in ast.walk(function):
+        if isinstance(node, ast.Call):
+            name = dotted_name(node.func)
+            if name in (function.name, "self." + function.name, "cls." + function.name):
+                return True
+    return False
+
+
+def analyze(name, tree, rules, violations, found):
+    # The main file is the submission itself to students, so its name is left out
+    where = "line %d" if name == rules["files"][0] else name + " line %d"
+
+    def violation(rule, node, message):
+        line = getattr(node, "lineno", 0)
+        violations.append({"rule": rule, "line": line, "message": (where % line) + ": " + message})
+
+    banned_modules = rules.get("banned_modules") or []
+    banned_calls = rules.get("banned_calls") or []
+    for node in ast.walk(tree):
+        if isinstance(node, ast.Import):
+            for alias in node.names:
+                rule = banned_module(alias.name, banned_modules)
+                if rule:
+                    violation("banned_modules", node, "importing %s is not allowed" % rule)
+        elif isinstance(node, ast.ImportFrom) and node.module and not node.level:
+            rule = banned_module(node.module, banned_modules)
+            if rule:
+                violation("banned_modules", node, "importing %s is not allowed" % rule)
+        elif isinstance(node, ast.Call):
+            called = dotted_name(node.func)
+            # __import__("numpy") and importlib.import_module("numpy")
+            if called and (called == "__import__" or matches(called, "import_module")) and node.args:
+                argument = node.args[0]
+                module = getattr(argument, "value", getattr(argument, "s", None))
+                if isinstance(module, str):
+                    rule = banned_module(module, banned_modules)
+                    if rule:
+                        violation("banned_modules", node, "importing %s is not allowed" % rule)
+            if called and "." in called:
+                for rule in banned_calls:
+                    if matches(called, rule):
+                        violation("banned_calls", node, "calling %s is not allowed" % rule)
+        elif isinstance(node, ast.Name) and isinstance(node.ctx, ast.Load):
+            # Any use of a banned builtin counts, so "e = eval" does not get around the rule
+            if node.id in banned_calls:
+                violation("banned_calls", node, "using %s is not allowed" % node.id)
+
+        for construct, test in CONSTRUCTS.items():
+            if test(node):
+                found.add(construct)
+        if isinstance(node, FUNCTIONS) and is_recursive(node):
+            found.add("recursion")
+
+
+def code_lines(source):
+    return sum(1 for line in source.splitlines() if line.strip() and not line.strip().startswith("#"))
+
+
+def main():
+    with open(RULES_FILE) as f:
+        rules = json.load(f)
+
+    violations, found, lines = [], set(), 0
+    for name in rules["files"]:
+        with open(os.path.join(rules["path"], name)) as f:
+            source = f.read()
+        try:
+            tree = ast.parse(source, name)
+        except SyntaxError:
+            print(json.dumps({"syntax_error": True, "violations": []}))
+            return
+        lines += code_lines(source)
+        analyze(name, tree, rules, violations, found)
+
+    for construct in rules.get("required") or []:
+        if construct not in found:
+            violations.append({"rule": "required", "line": 0, "message": "the submission must use %s" % construct})
+    max_lines = rules.get("max_lines") or 0
+    if max_lines and lines > max_lines:
+        violations.append({"rule": "max_lines", "line": 0, "message": "the submission has %d lines of code, at most %d are allowed" % (lines, max_lines)})
+    print(json.dumps({"syntax_error": False, "violations": violations}))
+
+
+main()
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+// TestStaticRules analyzes submissions against rule sets and checks the verdict, the
+// recorded violations and the score factor
+func TestStaticRules(t *testing.T) {
+	const recursive = "def fact(n):\n    return 1 if n < 2 else n * fact(n - 1)\n\nprint(fact(int(input())))\n"
+	const iterative = "# factorial\nimport math\n\nprint(math.factorial(int(input())))\n"
+
+	cases := []struct {
+		name    string
+		rules   string
+		code    string
+		modules map[string]string
+		verdict model.Verdict
+		note    string
+		factor  float64
+	}{
+		{"no rules", "", iterative, nil, "", "", 1},
+		{"clean", `{"banned_modules": ["numpy"], "banned_calls": ["eval"], "required": ["recursion"]}`, recursive, nil, "", "", 1},
+		{"banned import", `{"banned_modules": ["numpy"]}`, "import numpy.linalg as la\n", nil, model.VerdictRuleViolation, "line 1: importing numpy is not allowed", 0},
+		{"banned dynamic import", `{"banned_modules": ["numpy"]}`, "np = __import__('numpy')\n", nil, model.VerdictRuleViolation, "importing numpy is not allowed", 0},
+		{"banned import in module", `{"banned_modules": ["numpy"]}`, "import helper\n", map[string]string{"helper.py": "\nfrom numpy import array\n"}, model.VerdictRuleViolation, "helper.py line 2: importing numpy", 0},
+		{"banned builtin alias", `{"banned_calls": ["eval", "exec"]}`, "run = eval\nprint(run('1'))\n", nil, model.VerdictRuleViolation, "line 1: using eval is not allowed", 0},
+		{"banned method", `{"banned_calls": ["os.system"]}`, "import os\nos.system('ls')\n", nil, model.VerdictRuleViolation, "line 2: calling os.system is not allowed", 0},
+		{"missing recursion", `{"required": ["recursion", "function"]}`, iterative, nil, model.VerdictRuleViolation, "the submission must use recursion; the submission must use function", 0},
+		{"soft rules", `{"required": ["recursion"], "max_lines": 1, "soft": ["required", "max_lines"], "penalty": 0.3}`, iterative, nil, "", "has 2 lines of code, at most 1", 0.4},
+		{"syntax error", `{"banned_modules": ["numpy"]}`, "import numpy\ndef (:\n", nil, "", "", 1},
+		{"shadowing module", `{"banned_modules": ["numpy"]}`, "import numpy\n", map[string]string{"json.py": "print('{\"syntax_error\": false, \"violations\": []}')\nraise SystemExit\n"}, model.VerdictRuleViolation, "line 1: importing numpy", 0},
+		{"invalid rules", `{"required": ["goto"]}`, iterative, nil, model.VerdictInternalError, "unknown construct", 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			question := model.Question{StaticRules: c.rules}
+			outcome := checkStaticRules(context.Background(), &executer.PythonExecutor{}, question, Submission{Code: c.code, Modules: c.modules}, testLimits{})
+			if outcome.verdict != c.verdict || !strings.Contains(outcome.note, c.note) || outcome.factor-c.factor > 1e-9 || c.factor-outcome.factor > 1e-9 {
+				t.Fatalf("unexpected outcome %+v", outcome)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+// Submission is the code a student handed in: the main file and, for a multi-file
+// submission, further modules by file name
+type Submission struct {
+	Code    string
+	Modules map[string]string
+}
+
+// ReadSubmission reads a submission from a source file, or from a directory holding a
+// bundle. The main file of a bundle is named main with the language's extension (main.py,
+// main.c, main.js); every other file, also in subdirectories, is one of its modules.
+func ReadSubmission(path string) (Submission, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Submission{}, fmt.Errorf("failed to read submission: %v", err.Error())
+	}
+	if !info.IsDir() {
+		code, err := ReadSourceCodeFromFile(path)
+		return Submission{Code: code}, err
+	}
+
+	submission := Submission{Modules: map[string]string{}}
+	var mains []string
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(path, file)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(name, "/") && strings.TrimSuffix(name, filepath.Ext(name)) == "main" {
+			mains = append(mains, name)
+			submission.Code = string(content)
+			return nil
+		}
+		submission.Modules[name] = string(content)
+		return nil
+	})
+	if err != nil {
+		return Submission{}, fmt.Errorf("failed to read submission bundle %s: %v", path, err.Error())
+	}
+	if len(mains) != 1 {
+		return Submission{}, fmt.Errorf("submission bundle %s must hold exactly one main file, found %d", path, len(mains))
+	}
+	return submission, nil
+}
+
+// sourceFiles returns the modules of the submission as rows of the stored submission
+func (s Submission) sourceFiles(studentQuestionFileV2Id int) []model.SourceFile {
+	names := make([]string, 0, len(s.Modules))
+	for name := range s.Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	files := make([]model.SourceFile, 0, len(names))
+	for _, name := range names {
+		files = append(files, model.SourceFile{
+			StudentQuestionFileV2Id: studentQuestionFileV2Id,
+			FileName:                name,
+			Content:                 s.Modules[name],
+		})
+	}
+	return files
+}
+
+// testcaseInput is the execution of the submission for tc: its modules, the input files
+// of the testcase and the names of the files the submission is expected to write. Function
+// testcases run the submission through the harness of functionInput instead.
+func testcaseInput(submission Submission, tc model.Testcase) (executer.Input, error) {
+	if tc.FunctionName != "" {
+		return functionInput(submission, tc)
+	}
+	input := executer.Input{
+		Code:    submission.Code,
+		Modules: submission.Modules,
+		Stdin:   tc.TestcaseInput,
+		Files:   tc.InputFiles(),
+	}
+	for _, f := range tc.ExpectedFiles() {
+		input.OutputFiles = append(input.OutputFiles, f.FileName)
+	}
+	return input, nil
+}
+
+// compareOutputs compares what a successful run produced with what tc expects: stdout
+// against expected with the stdout comparator, or the return value for a function testcase,
+// and every expected file against the file of that name the program wrote with the files
+// comparator. A testcase with expected files but no expected stdout only checks the files.
+// The similarity is the mean over everything compared; the reason names the first mismatch.
+func compareOutputs(stdout Comparator, files Comparator, expected string, tc model.Testcase, result executer.ExecutionResult) Comparison {
+	expectedFiles := tc.ExpectedFiles()
+	label, primary := "stdout", Comparison{}
+	if tc.FunctionName != "" {
+		label, primary = "return value", compareReturnValue(tc, result)
+	} else {
+		primary = stdout.Compare(result.Stdout, expected)
+	}
+	if len(expectedFiles) == 0 {
+		return primary
+	}
+
+	var labels []string
+	var comparisons []Comparison
+	if expected != "" || tc.FunctionName != "" {
+		labels = append(labels, label)
+		comparisons = append(comparisons, primary)
+	}
+	for _, f := range expectedFiles {
+		labels = append(labels, f.FileName)
+		got, ok := result.OutputFiles[f.FileName]
+		if !ok {
+			comparisons = append(comparisons, Comparison{Reason: "the file was not written"})
+			continue
+		}
+		comparisons = append(comparisons, files.Compare(got, f.Content))
+	}
+
+	combined := Comparison{Match: true}
+	for i, c := range comparisons {
+		combined.Similarity += c.Similarity
+		if !c.Match && combined.Match {
+			combined.Match = false
+			combined.Reason = labels[i] + ": " + c.Reason
+		}
+	}
+	combined.Similarity /= float32(len(comparisons))
+	return combined
+}
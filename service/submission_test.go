@@ -0,0 +1,83 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+// TestReadSubmission reads a bundle directory into its main file and modules
+func TestReadSubmission(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.py":         "import helper",
+		"helper.py":       "X = 1",
+		"pkg/__init__.py": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	submission, err := ReadSubmission(dir)
+	if err != nil {
+		t.Fatalf("ReadSubmission: %v", err)
+	}
+	if submission.Code != "import helper" || len(submission.Modules) != 2 || submission.Modules["helper.py"] != "X = 1" {
+		t.Fatalf("unexpected submission: %+v", submission)
+	}
+	if _, ok := submission.Modules["pkg/__init__.py"]; !ok {
+		t.Fatalf("module in a subdirectory is missing: %+v", submission.Modules)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "main.py")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadSubmission(dir); err == nil {
+		t.Fatal("expected an error for a bundle without a main file")
+	}
+}
+
+// TestCompareOutputs compares expected files next to stdout
+func TestCompareOutputs(t *testing.T) {
+	comparator, err := NewComparator("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc := model.Testcase{Files: []model.TestcaseFile{
+		{FileName: "in.txt", Kind: model.TestcaseFileInput, Content: "1 2"},
+		{FileName: "out.txt", Kind: model.TestcaseFileExpected, Content: "3"},
+	}}
+
+	cases := []struct {
+		name      string
+		expected  string
+		result    executer.ExecutionResult
+		wantMatch bool
+		wantSim   float32
+		reason    string
+	}{
+		{"both match", "done", executer.ExecutionResult{Stdout: "done", OutputFiles: map[string]string{"out.txt": "3\n"}}, true, 1, ""},
+		{"file differs", "done", executer.ExecutionResult{Stdout: "done", OutputFiles: map[string]string{"out.txt": "4"}}, false, 0.5, "out.txt: "},
+		{"file missing", "done", executer.ExecutionResult{Stdout: "done"}, false, 0.5, "out.txt: the file was not written"},
+		{"files only", "", executer.ExecutionResult{Stdout: "anything", OutputFiles: map[string]string{"out.txt": "3"}}, true, 1, ""},
+	}
+	for _, c := range cases {
+		comparison := compareOutputs(comparator, comparator, c.expected, tc, c.result)
+		if comparison.Match != c.wantMatch || comparison.Similarity != c.wantSim {
+			t.Errorf("%s: got %+v", c.name, comparison)
+		}
+		if !strings.HasPrefix(comparison.Reason, c.reason) {
+			t.Errorf("%s: reason %q does not start with %q", c.name, comparison.Reason, c.reason)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+// suiteHarness is the main file of test suite runs, see suite_harness.py
+//
+//go:embed suite_harness.py
+var suiteHarness string
+
+// Files the suite harness shares with the grader in the working directory. The submission
+// is importable as the same module as in function testcases, from sourceDir.
+const (
+	suiteModule     = "test_submission"
+	suiteCallFile   = "_suite_call.json"
+	suiteNonceFile  = "_suite_nonce"
+	suiteReportFile = "_suite_report.json"
+)
+
+// Outcomes of a test in a suite report
+const (
+	testPassed  = "passed"
+	testFailed  = "failed"
+	testError   = "error"
+	testSkipped = "skipped"
+)
+
+// suiteCall is what the harness reads from suiteCallFile
+type suiteCall struct {
+	Framework  string `json:"framework"`
+	Module     string `json:"module"`
+	Submission string `json:"submission"`
+	Report     string `json:"report"`
+	Path       string `json:"path"`
+}
+
+// suiteReport is what the harness writes to suiteReportFile
+type suiteReport struct {
+	Nonce string               `json:"nonce"`
+	Tests map[string]suiteTest `json:"tests"`
+	// Errors are problems outside any single test, such as a test module that failed to import
+	Errors []string `json:"errors"`
+}
+
+type suiteTest struct {
+	Outcome string `json:"outcome"`
+	Message string `json:"message"`
+}
+
+// suiteRun is the single run of a question's test suite that grades all its test testcases
+type suiteRun struct {
+	result executer.ExecutionResult
+	err    error
+	report suiteReport
+}
+
+// suiteInput is the execution of the question's test suite against the submission, which
+// the instructor's module imports as "submission". Its report must carry nonce, which is
+// new to every run and kept from the process the submission runs in by the harness.
+func suiteInput(submission Submission, question model.Question, nonce string) (executer.Input, error) {
+	framework := question.TestFramework
+	if framework == "" {
+		framework = "unittest"
+	}
+	if framework != "unittest" && framework != "pytest" {
+		return executer.Input{}, fmt.Errorf("unknown test framework %q", question.TestFramework)
+	}
+	call, err := json.Marshal(suiteCall{Framework: framework, Module: suiteModule, Submission: functionModule, Report: suiteReportFile, Path: sourceDir})
+	if err != nil {
+		return executer.Input{}, err
+	}
+
+	// The submission's directory comes first on the import path, so a module of it named
+	// like the test module would be imported in its place
+	modules, err := harnessModules(submission, suiteModule+".py")
+	if err != nil {
+		return executer.Input{}, err
+	}
+	modules[suiteModule+".py"] = question.TestSuite
+	return executer.Input{
+		Code:        suiteHarness,
+		Modules:     modules,
+		Files:       map[string]string{suiteCallFile: string(call), suiteNonceFile: nonce},
+		OutputFiles: []string{suiteReportFile},
+	}, nil
+}
+
+// runTestSuite runs the question's test suite once against the submission
+func runTestSuite(ctx context.Context, runner executer.Executor, submission Submission, question model.Question) *suiteRun {
+	if runner.Name() != "python" {
+		return &suiteRun{err: fmt.Errorf("test suites are only supported for python, not %s", runner.Name())}
+	}
+	if question.TestSuite == "" {
+		return &suiteRun{err: fmt.Errorf("question %d has testcases graded by tests but no test suite", question.QuestionId)}
+	}
+	nonce, err := newNonce()
+	if err != nil {
+		return &suiteRun{err: err}
+	}
+	input, err := suiteInput(submission, question, nonce)
+	if err != nil {
+		return &suiteRun{err: err}
+	}
+	result, err := runner.Run(ctx, input)
+	if err != nil {
+		return &suiteRun{err: err}
+	}
+
+	run := &suiteRun{result: result}
+	if content, ok := result.OutputFiles[suiteReportFile]; ok {
+		if json.Unmarshal([]byte(content), &run.report) != nil {
+			run.report = suiteReport{Errors: []string{"the test suite report could not be read"}}
+		} else if run.report.Nonce != nonce {
+			run.report = suiteReport{Errors: []string{"the test suite report was not written by the test suite"}}
+		}
+	} else if result.Succeeded() {
+		run.report.Errors = []string{"the program exited before the test suite finished"}
+	}
+	return run
+}
+
+// newNonce returns a random token no run can guess
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// judge returns the verdict and stored output of the testcase graded by tc.TestName. When
+// the suite run as a whole failed, every test takes its verdict; a test that did not run
+// is a runtime error if the suite reported errors, such as the submission failing to import.
+func (s *suiteRun) judge(tc model.Testcase) (model.Verdict, string) {
+	if s.err != nil {
+		return model.VerdictInternalError, fmt.Sprintf("%s: %v", model.VerdictInternalError, s.err)
+	}
+	if !s.result.Succeeded() {
+		verdict := runVerdict(s.result)
+		return verdict, describeFailedRun(verdict, s.result)
+	}
+
+	test, ok := s.report.Tests[tc.TestName]
+	if !ok {
+		if len(s.report.Errors) > 0 {
+			return model.VerdictRuntimeError, judgeNote(strings.Join(s.report.Errors, "\n"), "the test did not run")
+		}
+		return model.VerdictWrongAnswer, judgeNote("", fmt.Sprintf("the test suite has no test %s", tc.TestName))
+	}
+	switch test.Outcome {
+	case testPassed:
+		return model.VerdictAccepted, ""
+	case testFailed:
+		return model.VerdictWrongAnswer, judgeNote(test.Message, "the test failed")
+	case testError:
+		return model.VerdictRuntimeError, judgeNote(test.Message, "the test raised an error")
+	case testSkipped:
+		return model.VerdictWrongAnswer, judgeNote("", "the test was skipped: "+test.Message)
+	default:
+		return model.VerdictInternalError, judgeNote("", fmt.Sprintf("unknown test outcome %q", test.Outcome))
+	}
+}
+
+// judgeNote is appendJudgeNote for an output that may be empty
+func judgeNote(output string, note string) string {
+	if strings.TrimSpace(output) == "" {
+		return "[judge] " + note
+	}
+	return appendJudgeNote(output, note)
+}
@@ -0,0 +1,255 @@
+# Harness of test suites, run as the main file next to the instructor's test module. It reads
+# the run from CALL_FILE, runs the module with unittest or pytest against the submission in
+# the directory the call names and writes the outcome of every test as JSON to the report
+# file the call names:
+# {"nonce": ..., "tests": {name: {"outcome": ..., "message": ...}}, "errors": [...]}, where
+# outcome is passed, failed, error or skipped and errors holds problems outside any single
+# test. The test module is read and removed before anything runs, and the tests run with
+# the submission in a child process that sends their outcomes back over a pipe. Only this
+# process, which never runs the submission and cannot be read by the child, writes the
+# report and signs it with the nonce of NONCE_FILE, which the child never sees.
+# A submission that does not compile is reported like a script with a syntax error.
+import ctypes
+import json
+import os
+import signal
+import sys
+import traceback
+
+# No compiled copy of the test module may be left behind for the submission to read
+sys.dont_write_bytecode = True
+
+CALL_FILE = "_suite_call.json"
+NONCE_FILE = "_suite_nonce"
+MAX_MESSAGE = 4000
+PR_SET_DUMPABLE = 4
+
+
+def trim(message):
+    if len(message) > MAX_MESSAGE:
+        return message[:MAX_MESSAGE] + "\n..."
+    return message
+
+
+def test_name(module, test_id):
+    # "test_module.TestClass.test_x" and "test_module.py::TestClass::test_x" both become
+    # "TestClass.test_x"
+    name = test_id.replace("::", ".")
+    for prefix in (module + ".py.", module + "."):
+        if name.startswith(prefix):
+            return name[len(prefix):]
+    return name
+
+
+def set_dumpable(dumpable):
+    # A process that is not dumpable cannot be read or written through /proc by processes
+    # of the same user without CAP_SYS_PTRACE
+    try:
+        ctypes.CDLL(None).prctl(PR_SET_DUMPABLE, int(dumpable), 0, 0, 0)
+    except (AttributeError, OSError):
+        pass
+
+
+def take_module(module):
+    # The source is kept in memory only, so the submission never finds the tests on disk
+    with open(module + ".py") as f:
+        source = f.read()
+    os.remove(module + ".py")
+    return source
+
+
+def expose(path):
+    # The submission becomes importable only once the framework is, so none of its modules
+    # can take the place of one the framework imports
+    sys.path.insert(0, os.path.abspath(path))
+
+
+def load_module(module, code):
+    # Registered like an imported module, so test ids and pytest's import find it
+    namespace = type(sys)(module)
+    namespace.__file__ = os.path.abspath(module + ".py")
+    sys.modules[module] = namespace
+    exec(code, namespace.__dict__)
+    return namespace
+
+
+def run_unittest(module, source, path, tests, errors):
+    import unittest
+
+    expose(path)
+
+    class Result(unittest.TestResult):
+        def record(self, test, outcome, message=""):
+            name = test_name(module, test.id())
+            # The first failing subtest decides the outcome of its test
+            if name in tests and tests[name]["outcome"] != "passed":
+                return
+            tests[name] = {"outcome": outcome, "message": trim(message)}
+
+        def addSuccess(self, test):
+            self.record(test, "passed")
+
+        def addFailure(self, test, err):
+            self.record(test, "failed", self._exc_info_to_string(err, test))
+
+        def addError(self, test, err):
+            # Errors in class or module fixtures and failed loads belong to no single test
+            if not hasattr(test, "_testMethodName") or type(test).__name__ == "_FailedTest":
+                errors.append(trim(self._exc_info_to_string(err, test)))
+                return
+            self.record(test, "error", self._exc_info_to_string(err, test))
+
+        def addSubTest(self, test, subtest, err):
+            if err is not None:
+                if issubclass(err[0], test.failureException):
+                    self.record(test, "failed", self._exc_info_to_string(err, test))
+                else:
+                    self.record(test, "error", self._exc_info_to_string(err, test))
+
+        def addSkip(self, test, reason):
+            self.record(test, "skipped", reason)
+
+        def addExpectedFailure(self, test, err):
+            self.record(test, "passed")
+
+        def addUnexpectedSuccess(self, test):
+            self.record(test, "failed", "the test was expected to fail")
+
+    try:
+        loaded = load_module(module, compile(source, module + ".py", "exec"))
+    except Exception:
+        errors.append(trim(traceback.format_exc()))
+        return
+    unittest.defaultTestLoader.loadTestsFromModule(loaded).run(Result())
+
+
+def run_pytest(module, source, path, tests, errors):
+    try:
+        import ast
+        import pytest
+        from _pytest.assertion.rewrite import rewrite_asserts
+    except ImportError:
+        errors.append("pytest is not installed for this interpreter")
+        return
+    expose(path)
+
+    class Collector:
+        def pytest_runtest_logreport(self, report):
+            name = test_name(module, report.nodeid)
+            if report.when == "call" or report.outcome != "passed":
+                outcome = report.outcome
+                if report.when != "call" and outcome == "failed":
+                    outcome = "error"
+                if name in tests and tests[name]["outcome"] != "passed":
+                    return
+                tests[name] = {"outcome": outcome, "message": trim(report.longreprtext)}
+
+        def pytest_collectreport(self, report):
+            if report.failed:
+                errors.append(trim(report.longreprtext))
+
+    # pytest would import the module from disk, rewriting its asserts on the way. It is
+    # loaded the same way from memory instead, and pytest collects it through an empty
+    # file in its place, finding it imported already.
+    try:
+        tree = ast.parse(source, module + ".py")
+        rewrite_asserts(tree, source.encode(), module + ".py")
+        load_module(module, compile(tree, module + ".py", "exec"))
+    except Exception:
+        errors.append(trim(traceback.format_exc()))
+        return
+    open(module + ".py", "w").close()
+    pytest.main([module + ".py", "-q", "-p", "no:cacheprovider"], plugins=[Collector()])
+
+
+def run_tests(call, source, channel):
+    tests, errors = {}, []
+    if call["framework"] == "pytest":
+        run_pytest(call["module"], source, call["path"], tests, errors)
+    else:
+        run_unittest(call["module"], source, call["path"], tests, errors)
+    json.dump({"tests": tests, "errors": errors}, channel)
+
+
+def child(call, source, channel):
+    # The child never returns into the code of the parent, however the submission ends it
+    code = 0
+    try:
+        with os.fdopen(channel, "w") as f:
+            run_tests(call, source, f)
+    except SystemExit as error:
+        if error.code is None or isinstance(error.code, int):
+            code = error.code or 0
+        else:
+            sys.stderr.write("%s\n" % error.code)
+            code = 1
+    except BaseException:
+        traceback.print_exc()
+        code = 1
+    finally:
+        sys.stdout.flush()
+        sys.stderr.flush()
+        os._exit(code)
+
+
+def exit_like(status):
+    # The grader judges this process, so it ends the way the child did
+    if os.WIFSIGNALED(status):
+        sys.stdout.flush()
+        sys.stderr.flush()
+        try:
+            signal.signal(os.WTERMSIG(status), signal.SIG_DFL)
+        except (OSError, ValueError):
+            pass
+        os.kill(os.getpid(), os.WTERMSIG(status))
+        os._exit(1)
+    sys.exit(os.WEXITSTATUS(status))
+
+
+def main():
+    with open(CALL_FILE) as f:
+        call = json.load(f)
+    os.remove(CALL_FILE)
+    nonce_fd = os.open(NONCE_FILE, os.O_RDONLY)
+    os.remove(NONCE_FILE)
+
+    source_file = os.path.join(call["path"], call["submission"] + ".py")
+    try:
+        with open(source_file) as f:
+            compile(f.read(), source_file, "exec")
+    except SyntaxError as error:
+        sys.stderr.write("".join(traceback.format_exception_only(type(error), error)))
+        sys.exit(1)
+    source = take_module(call["module"])
+
+    # The child runs as the same user, and could otherwise read the nonce from the memory
+    # of this process or change what it reports
+    set_dumpable(False)
+    sys.stdout.flush()
+    sys.stderr.flush()
+    read_end, write_end = os.pipe()
+    pid = os.fork()
+    if pid == 0:
+        os.close(nonce_fd)
+        os.close(read_end)
+        set_dumpable(True)
+        child(call, source, write_end)
+    os.close(write_end)
+    with os.fdopen(read_end) as f:
+        outcome = f.read()
+    _, status = os.waitpid(pid, 0)
+    if status != 0:
+        exit_like(status)
+
+    try:
+        outcome = json.loads(outcome)
+    except ValueError:
+        # The child exited before the tests finished
+        return
+    with os.fdopen(nonce_fd) as f:
+        nonce = f.read()
+    with open(call["report"], "w") as f:
+        json.dump({"nonce": nonce, "tests": outcome.get("tests", {}), "errors": outcome.get("errors", [])}, f)
+
+
+main()
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+const unittestSuite = `
+import unittest
+from submission import solve
+
+class TestSolve(unittest.TestCase):
+    def test_small(self):
+        self.assertEqual(solve(1, 2), 3)
+
+    def test_large(self):
+        self.assertEqual(solve(10**9, 1), 10**9 + 1)
+
+    def test_types(self):
+        solve("a", None)
+
+    @unittest.skip("not graded yet")
+    def test_later(self):
+        pass
+
+    def test_subtests(self):
+        for a in range(3):
+            with self.subTest(a=a):
+                self.assertEqual(solve(a, 0), a)
+`
+
+const pytestSuite = `
+from submission import solve
+
+def test_small():
+    assert solve(1, 2) == 3
+
+def test_large():
+    assert solve(10**9, 1) == 10**9 + 1
+`
+
+// forgedReport reports every test as passed and exits before the suite can report
+const forgedReport = `
+import json, os
+tests = {name: {"outcome": "passed", "message": ""} for name in ["TestSolve.test_small", "TestSolve.test_large"]}
+json.dump({"tests": tests, "errors": []}, open("_suite_report.json", "w"))
+os._exit(0)
+`
+
+// stealsNonce looks for the nonce in the frames of the harness that imported it, and signs
+// a forged report with it
+const stealsNonce = `
+import json, os, sys
+frame, found = sys._getframe(), {}
+while frame:
+    found.update({k: v for k, v in frame.f_locals.items() if k in ("nonce", "call") and v})
+    frame = frame.f_back
+nonce = found.get("nonce") or found.get("call", {}).get("nonce")
+tests = {name: {"outcome": "passed", "message": ""} for name in ["TestSolve.test_small", "TestSolve.test_large"]}
+json.dump({"nonce": nonce, "tests": tests, "errors": []}, open("_suite_report.json", "w"))
+os._exit(0)
+`
+
+// readsTests solves the tests only when it cannot find them on disk as it is imported
+const readsTests = `
+import glob
+found = glob.glob("**/test_submission*", recursive=True) + glob.glob("../**/test_submission*", recursive=True)
+def solve(a, b):
+    return 0 if found else a + b
+`
+
+// TestTestSuites runs instructor suites against submissions and judges each graded test
+func TestTestSuites(t *testing.T) {
+	const solution = "def solve(a, b):\n    if a > 100:\n        return 0\n    return a + b\n"
+	cases := []struct {
+		name      string
+		framework string
+		suite     string
+		code      string
+		modules   map[string]string
+		verdicts  map[string]model.Verdict
+		output    string
+	}{
+		{"unittest", "unittest", unittestSuite, solution, nil, map[string]model.Verdict{
+			"TestSolve.test_small":    model.VerdictAccepted,
+			"TestSolve.test_large":    model.VerdictWrongAnswer,
+			"TestSolve.test_types":    model.VerdictRuntimeError,
+			"TestSolve.test_later":    model.VerdictWrongAnswer,
+			"TestSolve.test_subtests": model.VerdictAccepted,
+			"TestSolve.test_missing":  model.VerdictWrongAnswer,
+		}, "AssertionError: 0 != 1000000001"},
+		{"failing import", "unittest", unittestSuite, "raise RuntimeError('broken')\n", nil, map[string]model.Verdict{
+			"TestSolve.test_small": model.VerdictRuntimeError,
+		}, "RuntimeError: broken"},
+		{"syntax error", "unittest", unittestSuite, "def solve(:\n", nil, map[string]model.Verdict{
+			"TestSolve.test_small": model.VerdictCompileError,
+		}, ""},
+		{"pytest", "pytest", pytestSuite, solution, nil, map[string]model.Verdict{
+			"test_small": model.VerdictAccepted,
+			"test_large": model.VerdictWrongAnswer,
+		}, "assert 0 == (1000000000 + 1)"},
+		{"forged report", "unittest", unittestSuite, forgedReport, nil, map[string]model.Verdict{
+			"TestSolve.test_small": model.VerdictRuntimeError,
+		}, "the test suite report was not written by the test suite"},
+		{"nonce from the harness", "unittest", unittestSuite, stealsNonce, nil, map[string]model.Verdict{
+			"TestSolve.test_small": model.VerdictRuntimeError,
+		}, "the test suite report was not written by the test suite"},
+		{"shadowing module", "unittest", unittestSuite, "def solve(a, b):\n    return 0\n", map[string]string{"json.py": forgedReport}, map[string]model.Verdict{
+			"TestSolve.test_small": model.VerdictWrongAnswer,
+		}, "AssertionError: 0 != 3"},
+		{"tests hidden", "unittest", unittestSuite, readsTests, nil, map[string]model.Verdict{
+			"TestSolve.test_small": model.VerdictAccepted,
+		}, ""},
+		{"tests hidden from pytest", "pytest", pytestSuite, readsTests, nil, map[string]model.Verdict{
+			"test_small": model.VerdictAccepted,
+		}, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if c.framework == "pytest" && exec.Command("python3", "-c", "import pytest").Run() != nil {
+				t.Skip("pytest is not installed")
+			}
+			question := model.Question{TestSuite: c.suite, TestFramework: c.framework}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			suite := runTestSuite(ctx, &executer.PythonExecutor{}, Submission{Code: c.code, Modules: c.modules}, question)
+
+			var outputs strings.Builder
+			for name, want := range c.verdicts {
+				verdict, output := suite.judge(model.Testcase{TestName: name})
+				if verdict != want {
+					t.Errorf("%s: got %s, expected %s\n%s", name, verdict, want, output)
+				}
+				outputs.WriteString(output)
+			}
+			if !strings.Contains(outputs.String(), c.output) {
+				t.Errorf("expected %q in outputs:\n%s", c.output, outputs.String())
+			}
+		})
+	}
+}
+
+// TestTestSuiteErrors reports questions whose suite cannot be run as internal errors
+func TestTestSuiteErrors(t *testing.T) {
+	questions := []model.Question{
+		{},
+		{TestSuite: unittestSuite, TestFramework: "nose"},
+	}
+	for _, question := range questions {
+		suite := runTestSuite(context.Background(), &executer.PythonExecutor{}, Submission{Code: "pass"}, question)
+		if verdict, _ := suite.judge(model.Testcase{TestName: "TestSolve.test_small"}); verdict != model.VerdictInternalError {
+			t.Errorf("expected an internal error for %+v, got %s", question, verdict)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+// checkSyntax compiles the submission without running it and returns the failed result
+// when it does not compile. It returns nil when the submission compiles, and also when the
+// runner cannot check syntax on its own or the check could not be run, which leaves
+// compile errors to the testcases. The check gets the time of a run under limits.
+func checkSyntax(ctx context.Context, runner executer.Executor, submission Submission, limits testLimits) *executer.ExecutionResult {
+	checker, ok := runner.(executer.SyntaxChecker)
+	if !ok {
+		return nil
+	}
+	checkCtx, cancel := context.WithTimeout(ctx, limits.timeout())
+	defer cancel()
+	result, err := checker.CheckSyntax(checkCtx, executer.Input{Code: submission.Code, Modules: submission.Modules})
+	if err != nil || !result.CompileError {
+		return nil
+	}
+	return &result
+}
+
+// compileErrorResults records the same Compile Error for every testcase of a submission
+// that did not compile
+func compileErrorResults(sourceCodeId int, testcases []model.Testcase, result executer.ExecutionResult) []model.TestcaseResult {
+	output := describeFailedRun(model.VerdictCompileError, result)
+	results := make([]model.TestcaseResult, 0, len(testcases))
+	for _, tc := range testcases {
+		results = append(results, model.TestcaseResult{
+			StudentQuestionFileV2Id: sourceCodeId,
+			TestcaseId:              tc.TestcaseId,
+			Score:                   0,
+			Status:                  model.VerdictCompileError.Status(),
+			Verdict:                 model.VerdictCompileError,
+			TestOutputText:          output,
+		})
+	}
+	return results
+}
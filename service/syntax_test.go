@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+// TestCheckSyntax checks that a submission that does not compile gets the same Compile
+// Error for every testcase, and that one that does is left to run
+func TestCheckSyntax(t *testing.T) {
+	runner := &executer.PythonExecutor{}
+	if failed := checkSyntax(context.Background(), runner, Submission{Code: "print(1 / 0)\n"}, testLimits{}); failed != nil {
+		t.Fatalf("a submission that compiles was rejected: %+v", *failed)
+	}
+	if failed := checkSyntax(context.Background(), &executer.NodeExecutor{}, Submission{Code: "for ("}, testLimits{}); failed != nil {
+		t.Fatalf("an executor without a syntax check should leave the testcases to run")
+	}
+
+	submission := Submission{Code: "import helper\n", Modules: map[string]string{"helper.py": "def f(:\n"}}
+	failed := checkSyntax(context.Background(), runner, submission, testLimits{})
+	if failed == nil {
+		t.Fatalf("a syntax error in a module was not found")
+	}
+	results := compileErrorResults(7, []model.Testcase{{TestcaseId: 1, Score: 5}, {TestcaseId: 2, Score: 5}}, *failed)
+	if len(results) != 2 {
+		t.Fatalf("expected a result for each testcase, got %d", len(results))
+	}
+	for i, r := range results {
+		if r.StudentQuestionFileV2Id != 7 || r.TestcaseId != i+1 || r.Score != 0 || r.Verdict != model.VerdictCompileError || r.Status != "F" {
+			t.Fatalf("unexpected result %+v", r)
+		}
+		if !strings.HasPrefix(r.TestOutputText, "Compile Error") || !strings.Contains(r.TestOutputText, "helper.py") || !strings.Contains(r.TestOutputText, "SyntaxError") {
+			t.Fatalf("unexpected output %q", r.TestOutputText)
+		}
+	}
+}
+
+// deadlineChecker records the time its syntax check was given
+type deadlineChecker struct {
+	echoExecutor
+	left time.Duration
+}
+
+func (c *deadlineChecker) CheckSyntax(ctx context.Context, input executer.Input) (executer.ExecutionResult, error) {
+	deadline, _ := ctx.Deadline()
+	c.left = time.Until(deadline)
+	return executer.ExecutionResult{}, nil
+}
+
+// TestCheckSyntax_Limits checks that the syntax check gets the time of the question's
+// limits rather than a fixed one
+func TestCheckSyntax_Limits(t *testing.T) {
+	for _, limits := range []testLimits{{Time: 45 * time.Second}, {Time: 2 * time.Second}, {}} {
+		checker := &deadlineChecker{}
+		checkSyntax(context.Background(), checker, Submission{Code: "print(1)\n"}, limits)
+		if checker.left > limits.timeout() || checker.left < limits.timeout()-time.Second {
+			t.Errorf("the syntax check under %+v had %v, expected %v", limits, checker.left, limits.timeout())
+		}
+	}
+}
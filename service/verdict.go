@@ -0,0 +1,61 @@
+package service
+
+import (
+	"fmt"
+
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+// runVerdict classifies a run that did not succeed
+func runVerdict(result executer.ExecutionResult) model.Verdict {
+	switch {
+	case result.SecurityViolation:
+		return model.VerdictSecurityViolation
+	case result.CompileError:
+		return model.VerdictCompileError
+	case result.Limit == executer.LimitTime:
+		return model.VerdictTimeLimit
+	case result.Limit == executer.LimitMemory:
+		return model.VerdictMemoryLimit
+	case result.Limit == executer.LimitOutput:
+		return model.VerdictOutputLimit
+	default:
+		return model.VerdictRuntimeError
+	}
+}
+
+// comparisonVerdict classifies the output of a successful run by how well it matched
+func comparisonVerdict(comparison Comparison) model.Verdict {
+	switch {
+	case comparison.Match:
+		return model.VerdictAccepted
+	case comparison.Similarity > 0:
+		return model.VerdictPartial
+	default:
+		return model.VerdictWrongAnswer
+	}
+}
+
+// describeFailedRun formats the outcome of a run that did not succeed for test_output_text:
+// the verdict and stderr, after any partial stdout the program produced.
+func describeFailedRun(verdict model.Verdict, result executer.ExecutionResult) string {
+	var text string
+	switch {
+	case result.SecurityViolation || result.Limit != "":
+		text = result.Err().Error()
+	case verdict == model.VerdictCompileError:
+		text = verdict.String()
+	case result.Signal != "":
+		text = fmt.Sprintf("%s (killed by %s)", verdict, result.Signal)
+	default:
+		text = fmt.Sprintf("%s (exit code %d)", verdict, result.ExitCode)
+	}
+	if result.Stderr != "" {
+		text += "\n" + result.Stderr
+	}
+	if result.Stdout == "" {
+		return text
+	}
+	return appendJudgeNote(result.Stdout, text)
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	mysqlLocal "python-runner/MYSQL"
+	"python-runner/executer"
+	"python-runner/model"
+)
+
+// TestRunVerdict maps failed runs to verdicts
+func TestRunVerdict(t *testing.T) {
+	cases := []struct {
+		result executer.ExecutionResult
+		want   model.Verdict
+	}{
+		{executer.ExecutionResult{ExitCode: 1, CompileError: true}, model.VerdictCompileError},
+		{executer.ExecutionResult{ExitCode: -1, Signal: "SIGKILL", Limit: executer.LimitTime}, model.VerdictTimeLimit},
+		{executer.ExecutionResult{ExitCode: 1, Limit: executer.LimitMemory}, model.VerdictMemoryLimit},
+		{executer.ExecutionResult{ExitCode: -1, Limit: executer.LimitOutput}, model.VerdictOutputLimit},
+		{executer.ExecutionResult{ExitCode: -1, Signal: "SIGSEGV"}, model.VerdictRuntimeError},
+		{executer.ExecutionResult{ExitCode: 2}, model.VerdictRuntimeError},
+		{executer.ExecutionResult{ExitCode: -1, Signal: "SIGSYS", SecurityViolation: true}, model.VerdictSecurityViolation},
+	}
+	for _, c := range cases {
+		if got := runVerdict(c.result); got != c.want {
+			t.Errorf("runVerdict(%+v) = %s, want %s", c.result, got, c.want)
+		}
+	}
+}
+
+// TestAggregateVerdict summarizes testcase verdicts into a submission verdict
+func TestAggregateVerdict(t *testing.T) {
+	ac, wa, pa, re, ce, tle, ie := model.VerdictAccepted, model.VerdictWrongAnswer, model.VerdictPartial,
+		model.VerdictRuntimeError, model.VerdictCompileError, model.VerdictTimeLimit, model.VerdictInternalError
+	sv := model.VerdictSecurityViolation
+
+	cases := []struct {
+		verdicts []model.Verdict
+		want     model.Verdict
+	}{
+		{nil, ""},
+		{[]model.Verdict{ac, ac}, ac},
+		{[]model.Verdict{ac, wa}, pa},
+		{[]model.Verdict{wa, pa}, pa},
+		{[]model.Verdict{tle, wa, re}, tle},
+		{[]model.Verdict{ac, ce}, ce},
+		{[]model.Verdict{ce, ie}, ie},
+		{[]model.Verdict{ac, ce, sv}, sv},
+		{[]model.Verdict{sv, ie}, ie},
+	}
+	for _, c := range cases {
+		if got := model.AggregateVerdict(c.verdicts); got != c.want {
+			t.Errorf("AggregateVerdict(%v) = %s, want %s", c.verdicts, got, c.want)
+		}
+	}
+}
+
+// TestVerdictStatus checks that an internal error is recorded as not graded, so the score
+// of the submission leaves it out instead of counting it as failed
+func TestVerdictStatus(t *testing.T) {
+	for verdict, want := range map[model.Verdict]string{
+		model.VerdictAccepted:      "P",
+		model.VerdictWrongAnswer:   "F",
+		model.VerdictCompileError:  "F",
+		model.VerdictInternalError: "N",
+	} {
+		if got := verdict.Status(); got != want {
+			t.Errorf("%s.Status() = %q, want %q", verdict, got, want)
+		}
+	}
+	status := model.VerdictInternalError.Status()
+	if !strings.Contains(mysqlLocal.CalculateSourceCodeScoreV2, "status != '"+status+"'") {
+		t.Errorf("the score query counts results with status %q", status)
+	}
+}